@@ -0,0 +1,341 @@
+package srfax
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SyncIndexName is the name of the checkpoint file Sync keeps in the archive directory.
+const SyncIndexName = ".srfax-sync.json"
+
+// SyncIndex is the checkpoint of an archive maintained by Sync. Entries are keyed by
+// direction and FaxFileName, e.g., IN/20170101230101-1212-21_7|12124720.
+type SyncIndex struct {
+	Updated time.Time
+	Faxes   map[string]*SyncEntry
+}
+
+// SyncEntry is a fax archived by Sync.
+type SyncEntry struct {
+	Record FaxRecord
+
+	// File name of the fax, relative to the archive directory
+	Path string
+
+	// Integrity of the file when it was archived
+	Integrity Integrity
+
+	// When the fax was archived
+	Synced time.Time
+
+	// When the fax was found to be deleted from SRFax, zero while it still exists
+	RemoteDeleted time.Time
+
+	// True if the fax was deleted from SRFax by Sync itself
+	DeletedBySync bool
+}
+
+func syncKey(r *FaxRecord) string { return r.Direction + "/" + r.FileName }
+
+// LoadSyncIndex reads the checkpoint of the archive in dir. A missing checkpoint yields an
+// empty index.
+func LoadSyncIndex(dir string) (*SyncIndex, error) {
+	idx := &SyncIndex{Faxes: make(map[string]*SyncEntry)}
+	b, err := ioutil.ReadFile(filepath.Join(dir, SyncIndexName))
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read sync index")
+	}
+	if err := json.Unmarshal(b, idx); err != nil {
+		return nil, errors.Wrap(err, "failed to decode sync index")
+	}
+	if idx.Faxes == nil {
+		idx.Faxes = make(map[string]*SyncEntry)
+	}
+	return idx, nil
+}
+
+// SyncOptions specify optional arguments for Sync.
+type SyncOptions struct {
+	// Directions to archive, defaults to both IN and OUT
+	Directions []string
+
+	// PDF or TIFF, defaults to PDF
+	FaxFormat string
+
+	// Number of faxes retrieved at once, defaults to 4
+	Concurrency int
+
+	// Include faxes of sub users of the account
+	IncludeSubUsers bool
+
+	// Delete faxes from SRFax once their local copy has been verified against the index
+	DeleteRemote bool
+
+	// Optional callback invoked after every fax retrieval, from multiple goroutines one at a time
+	Progress func(DownloadProgress)
+}
+
+// SyncReport summarizes a Sync run. Faxes are identified by their SyncIndex key.
+type SyncReport struct {
+	// Faxes archived during this run
+	Archived []string
+
+	// Faxes that could not be archived, they are retried on the next run
+	Failed map[string]error
+
+	// Archived faxes found to be deleted from SRFax since the last run
+	RemoteDeleted []string
+
+	// Faxes deleted from SRFax after their local copy was verified
+	Deleted []string
+}
+
+// Sync mirrors the inbox and outbox into dir. Every fax not yet archived is retrieved,
+// checked with CheckIntegrity and recorded in a checkpoint file, so an interrupted run
+// resumes where it left off and later runs only retrieve new faxes. Archived faxes that no
+// longer show up in SRFax are marked as deleted in the index, their local copy is kept.
+//
+// With DeleteRemote, archived faxes are deleted from SRFax, but only after the local file
+// matches the size and digest recorded in the index.
+//
+// Failures of individual faxes are reported in the SyncReport, an error is returned if the
+// faxes could not be listed or the checkpoint could not be written.
+func (c *Client) Sync(ctx context.Context, dir string, opts SyncOptions) (*SyncReport, error) {
+	if len(opts.Directions) == 0 {
+		opts.Directions = []string{inbound, outbound}
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.FaxFormat == "" {
+		opts.FaxFormat = "PDF"
+	}
+	if !(opts.FaxFormat == "PDF" || opts.FaxFormat == "TIFF") {
+		return nil, errors.New("FaxFormat must be one of: PDF or TIFF")
+	}
+	for _, d := range opts.Directions {
+		if !(d == inbound || d == outbound) {
+			return nil, errors.Errorf("Directions must be one of: %s or %s", inbound, outbound)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create archive directory")
+	}
+	idx, err := LoadSyncIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &syncer{c: c, dir: dir, opts: &opts, idx: idx, report: &SyncReport{Failed: make(map[string]error)}, saved: time.Now()}
+	for _, d := range opts.Directions {
+		if err := s.syncDirection(ctx, d); err != nil {
+			return s.report, err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.report, s.save()
+}
+
+type syncer struct {
+	c    *Client
+	dir  string
+	opts *SyncOptions
+
+	mu      sync.Mutex // guards the fields below
+	idx     *SyncIndex
+	report  *SyncReport
+	unsaved int       // faxes archived since the checkpoint was saved
+	saved   time.Time // when the checkpoint was saved
+}
+
+// The checkpoint is saved after syncSaveEvery archived faxes or syncSaveInterval, whichever
+// comes first, and once a direction is done. Faxes archived after the last save are verified
+// and adopted by archive when the sync is resumed.
+const (
+	syncSaveEvery    = 100
+	syncSaveInterval = 10 * time.Second
+)
+
+// save writes the checkpoint, s.mu must be held.
+func (s *syncer) save() error {
+	s.idx.Updated = time.Now()
+	if err := writeJSONFile(filepath.Join(s.dir, SyncIndexName), s.idx); err != nil {
+		return errors.Wrap(err, "failed to save sync index")
+	}
+	s.unsaved = 0
+	s.saved = time.Now()
+	return nil
+}
+
+func (s *syncer) syncDirection(ctx context.Context, direction string) error {
+	records, err := s.c.Faxes(ctx, FaxQuery{Direction: direction, IncludeSubUsers: s.opts.IncludeSubUsers}).All()
+	if err != nil {
+		return err
+	}
+
+	listed := make(map[string]bool, len(records))
+	var todo []FaxRecord
+	for _, r := range records {
+		key := syncKey(&r)
+		listed[key] = true
+		if e, ok := s.idx.Faxes[key]; ok {
+			if _, err := os.Stat(filepath.Join(s.dir, e.Path)); err == nil {
+				continue
+			}
+		}
+		todo = append(todo, r)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		saveErr  error
+		work     = make(chan FaxRecord)
+		progress int
+	)
+	for i := 0; i < s.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range work {
+				e, err := s.archive(ctx, r)
+
+				s.mu.Lock()
+				key := syncKey(&r)
+				if err != nil {
+					s.report.Failed[key] = err
+				} else {
+					s.idx.Faxes[key] = e
+					s.report.Archived = append(s.report.Archived, key)
+					s.unsaved++
+					if s.unsaved >= syncSaveEvery || time.Since(s.saved) >= syncSaveInterval {
+						if err := s.save(); err != nil && saveErr == nil {
+							saveErr = err
+						}
+					}
+				}
+				progress++
+				if s.opts.Progress != nil {
+					s.opts.Progress(DownloadProgress{Record: r, Path: filepath.Join(s.dir, downloadName(r, s.opts.FaxFormat)), Err: err, Done: progress, Total: len(todo)})
+				}
+				s.mu.Unlock()
+			}
+		}()
+	}
+	for _, r := range todo {
+		work <- r
+	}
+	close(work)
+	wg.Wait()
+	if saveErr != nil {
+		return saveErr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The listing is complete, archived faxes missing from it were deleted from SRFax.
+	now := time.Now()
+	for key, e := range s.idx.Faxes {
+		if e.Record.Direction == direction && !listed[key] && e.RemoteDeleted.IsZero() {
+			e.RemoteDeleted = now
+			s.report.RemoteDeleted = append(s.report.RemoteDeleted, key)
+		}
+	}
+	sort.Strings(s.report.RemoteDeleted)
+	if err := s.save(); err != nil {
+		return err
+	}
+	if s.opts.DeleteRemote {
+		return s.deleteRemote(ctx, direction, listed)
+	}
+	return nil
+}
+
+// archive retrieves a single fax into the archive directory. A file left behind by an
+// interrupted run is verified and adopted instead of being retrieved again.
+func (s *syncer) archive(ctx context.Context, r FaxRecord) (*SyncEntry, error) {
+	name := downloadName(r, s.opts.FaxFormat)
+	path := filepath.Join(s.dir, name)
+	integrity, err := checkFile(path, r.Pages)
+	if err != nil {
+		integrity, err = s.c.RetrieveFaxVerified(ctx, r.Ref(), path, VerifyOptions{ExpectPages: r.Pages}, RetrieveOptions{FaxFormat: s.opts.FaxFormat})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &SyncEntry{Record: r, Path: name, Integrity: *integrity, Synced: time.Now()}, nil
+}
+
+// checkFile runs CheckIntegrity on the file at path, expecting the given page count if non-zero.
+func checkFile(path string, pages int) (*Integrity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	integrity, err := CheckIntegrity(f, info.Size())
+	if err != nil {
+		return nil, err
+	}
+	if err := integrity.Verify(0, pages); err != nil {
+		return nil, err
+	}
+	return integrity, nil
+}
+
+// deleteRemote deletes listed faxes from SRFax whose local copy still matches the index,
+// in batches. s.mu must be held.
+func (s *syncer) deleteRemote(ctx context.Context, direction string, listed map[string]bool) error {
+	const batchSize = 50
+	var keys []string
+	for key, e := range s.idx.Faxes {
+		if e.Record.Direction != direction || !listed[key] || !e.RemoteDeleted.IsZero() {
+			continue
+		}
+		integrity, err := checkFile(filepath.Join(s.dir, e.Path), 0)
+		if err != nil || integrity.Size != e.Integrity.Size || integrity.SHA256 != e.Integrity.SHA256 {
+			s.report.Failed[key] = errors.Errorf("local copy %s does not match the index, not deleting", e.Path)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, batch := range chunkStrings(keys, batchSize) {
+		names := make([]string, len(batch))
+		for i, key := range batch {
+			names[i] = s.idx.Faxes[key].Record.FileName
+		}
+		if _, err := s.c.deleteFax(ctx, names, direction); err != nil {
+			for _, key := range batch {
+				s.report.Failed[key] = errors.Wrap(err, "failed to delete fax")
+			}
+			continue
+		}
+		now := time.Now()
+		for _, key := range batch {
+			e := s.idx.Faxes[key]
+			e.RemoteDeleted, e.DeletedBySync = now, true
+			s.report.Deleted = append(s.report.Deleted, key)
+		}
+		if err := s.save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package srfax
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSync(t *testing.T) {
+	pdf, err := (&CoverPage{ToName: "Road Runner"}).PDF()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		a = "20180101230101-8812-34_0|100"
+		b = "20180102230101-8812-34_0|101"
+	)
+	var (
+		mu        sync.Mutex
+		inbox     = map[string]bool{a: true, b: true}
+		retrieves = make(map[string]int)
+		deleted   []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		var resp map[string]interface{}
+		switch req["action"] {
+		case actionGetFaxInbox:
+			result := make([]map[string]interface{}, 0)
+			for name := range inbox {
+				result = append(result, map[string]interface{}{"FileName": name, "CallerID": "6135550000", "Pages": 1})
+			}
+			resp = map[string]interface{}{"Status": "Success", "Result": result}
+		case actionGetFaxOutbox:
+			resp = map[string]interface{}{"Status": "Success", "Result": []interface{}{}}
+		case actionRetrieveFax:
+			name := req["sFaxFileName"].(string)
+			retrieves[name]++
+			if name == b && retrieves[name] == 1 {
+				resp = map[string]interface{}{"Status": "Failed", "Result": "Temporarily unavailable"}
+				break
+			}
+			resp = map[string]interface{}{"Status": "Success", "Result": base64.StdEncoding.EncodeToString(pdf)}
+		case actionDeleteFax:
+			for k, v := range req {
+				if strings.HasPrefix(k, "sFaxFileName_") {
+					delete(inbox, v.(string))
+					deleted = append(deleted, v.(string))
+				}
+			}
+			resp = map[string]interface{}{"Status": "Success", "Result": ""}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	client := &Client{account: account{9090, "abc"}, url: srv.URL}
+	ctx := context.Background()
+	syncOnce := func(opts SyncOptions) *SyncReport {
+		t.Helper()
+		report, err := client.Sync(ctx, dir, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+
+	report := syncOnce(SyncOptions{})
+	if fmt.Sprint(report.Archived) != "[IN/"+a+"]" || len(report.Failed) != 1 {
+		t.Fatalf("want a archived and b failed; got %+v", report)
+	}
+
+	report = syncOnce(SyncOptions{})
+	if fmt.Sprint(report.Archived) != "[IN/"+b+"]" || len(report.Failed) != 0 {
+		t.Fatalf("want b archived on the next run; got %+v", report)
+	}
+
+	// a lost checkpoint is rebuilt from the files on disk without retrieving them again
+	if err := os.Remove(filepath.Join(dir, SyncIndexName)); err != nil {
+		t.Fatal(err)
+	}
+	report = syncOnce(SyncOptions{Directions: []string{"IN"}})
+	if len(report.Archived) != 2 || retrieves[a] != 1 || retrieves[b] != 2 {
+		t.Fatalf("want files adopted without retrieval; got %+v, retrieves %v", report, retrieves)
+	}
+
+	mu.Lock()
+	delete(inbox, a)
+	mu.Unlock()
+	report = syncOnce(SyncOptions{})
+	if fmt.Sprint(report.RemoteDeleted) != "[IN/"+a+"]" || len(report.Archived) != 0 {
+		t.Fatalf("want a detected as deleted; got %+v", report)
+	}
+
+	report = syncOnce(SyncOptions{DeleteRemote: true})
+	if fmt.Sprint(report.Deleted) != "[IN/"+b+"]" || fmt.Sprint(deleted) != "["+b+"]" {
+		t.Fatalf("want b deleted from SRFax; got %+v, deleted %v", report, deleted)
+	}
+
+	idx, err := LoadSyncIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := idx.Faxes["IN/"+b]; e == nil || !e.DeletedBySync || e.RemoteDeleted.IsZero() || e.Integrity.Pages != 1 {
+		t.Fatalf("unexpected index entry %+v", e)
+	}
+	if _, err := os.Stat(filepath.Join(dir, idx.Faxes["IN/"+a].Path)); err != nil {
+		t.Fatal("want local copy of a deleted fax kept")
+	}
+}
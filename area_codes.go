@@ -0,0 +1,104 @@
+package srfax
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// areaCodeZones maps IANA timezones to the NANP area codes that are (at least partly)
+// served by that timezone. An area code that spans several timezones is listed under each one.
+var areaCodeZones = map[string]string{
+	// Eastern
+	"America/New_York": `
+		201 202 203 207 212 215 216 220 223 227 229 231 234 239 240 248 252 260 267 269 272 276 283 301
+		302 304 305 313 315 317 321 324 326 329 330 332 336 339 347 351 352 363 380 386 401 404 407
+		410 412 413 419 423 434 436 440 443 445 448 463 470 472 475 478 484 502 508 513 516 517
+		518 540 551 561 567 570 571 574 582 585 586 603 606 607 609 610 614 616 617 624 631 640 645
+		646 656 667 678 679 680 681 686 689 703 704 706 716 717 718 724 727 732 734 740 743 754 757
+		762 765 770 771 772 774 781 786 802 803 804 810 812 813 814 826 828 835 838 839 843 845 848
+		850 854 856 857 859 860 862 863 864 865 878 904 906 908 910 912 914 917 919 929 930 934
+		937 941 943 947 948 954 959 973 978 980 984 989`,
+	"America/Toronto": `
+		226 249 263 289 343 354 365 367 382 416 418 437 438 450 468 514 519 548 579 581 613 647 683
+		705 742 753 807 819 867 873 905`,
+
+	// Central
+	"America/Chicago": `
+		205 210 214 217 218 219 224 225 228 251 254 256 262 270 274 281 308 309 312 314 316 318 319
+		320 325 331 334 337 346 361 364 402 405 409 414 417 430 432 447 448 464 469 479 501 504 507 512
+		515 531 534 539 557 563 572 573 580 601 605 608 612 615 618 620 629 630 636 641 651 659 660
+		662 682 701 708 712 713 715 726 730 731 737 763 769 773 779 785 806 812 815 816 817 830 832
+		847 850 870 872 901 903 906 913 918 920 930 931 936 938 940 945 952 956 972 975 979 985`,
+	"America/Winnipeg": `204 431 584 807 867`,
+	"America/Regina":   `306 474 639`,
+
+	// Mountain
+	"America/Denver": `
+		208 303 307 308 385 406 435 458 505 541 575 605 620 701 719 720 785 801 915 970 983 986`,
+	"America/Phoenix":  `480 520 602 623 928`,
+	"America/Edmonton": `368 403 587 780 825 867`,
+
+	// Pacific
+	"America/Los_Angeles": `
+		206 208 209 213 253 279 310 323 341 350 360 369 408 415 424 425 442 458 503 509 510 530 541
+		559 562 564 619 626 628 650 657 661 669 702 707 714 725 747 760 775 805 818 820 831 837 840
+		858 909 916 925 949 951 971 986`,
+	"America/Vancouver":  `236 250 257 604 672 778`,
+	"America/Whitehorse": `867`,
+
+	// Atlantic, Alaska, Hawaii and US territories
+	"America/Halifax":     `428 506 782 902`,
+	"America/St_Johns":    `709 879`,
+	"America/Anchorage":   `907`,
+	"Pacific/Honolulu":    `808`,
+	"America/Puerto_Rico": `340 787 939`,
+	"Pacific/Guam":        `670 671`,
+	"Pacific/Pago_Pago":   `684`,
+
+	// Caribbean NANP countries
+	"America/Nassau":        `242`,
+	"America/Barbados":      `246`,
+	"America/Port_of_Spain": `264 268 284 473 664 721 758 767 784 868 869`,
+	"America/Cayman":        `345`,
+	"Atlantic/Bermuda":      `441`,
+	"America/Grand_Turk":    `649`,
+	"America/Santo_Domingo": `809 829 849`,
+	"America/Jamaica":       `658 876`,
+}
+
+// areaCodeTimezones maps each area code to its timezone(s), the inverse of areaCodeZones.
+var areaCodeTimezones map[string][]string
+
+func init() {
+	areaCodeTimezones = make(map[string][]string)
+	for zone, codes := range areaCodeZones {
+		for _, code := range strings.Fields(codes) {
+			areaCodeTimezones[code] = append(areaCodeTimezones[code], zone)
+		}
+	}
+}
+
+// AreaCodeLocations returns the timezone(s) of a NANP area code. Area codes that span
+// more than one timezone return all of them. Returns an error if the area code is
+// unknown or the timezone database is unavailable.
+//
+// Timezones are loaded with time.LoadLocation, which needs the zoneinfo database of the
+// host. Programs running where it may be missing, e.g., minimal containers or Windows
+// without Go installed, should embed it by importing time/tzdata (Go 1.15 or later).
+func AreaCodeLocations(areaCode string) ([]*time.Location, error) {
+	zones, ok := areaCodeTimezones[areaCode]
+	if !ok {
+		return nil, errors.Errorf("unknown area code: %q", areaCode)
+	}
+	locs := make([]*time.Location, 0, len(zones))
+	for _, z := range zones {
+		loc, err := time.LoadLocation(z)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load timezone %s for area code %s", z, areaCode)
+		}
+		locs = append(locs, loc)
+	}
+	return locs, nil
+}
@@ -0,0 +1,406 @@
+package srfax
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ForwardEntry records an inbound fax handled by an AutoForwarder.
+type ForwardEntry struct {
+	// FaxFileName and sender of the inbound fax
+	FileName string
+	CallerID string
+
+	// Recipients in sToFaxNumber wire format
+	To []string
+
+	// sAccountCode the fax was forwarded with, and when
+	AccountCode string
+	Forwarded   time.Time
+
+	// FaxDetailsIDs of the outbound faxes
+	IDs []int `json:",omitempty"`
+
+	// True while the ForwardFax request is in progress
+	Pending bool `json:",omitempty"`
+
+	// Why the fax was not forwarded, e.g., to prevent a forwarding loop
+	Blocked string `json:",omitempty"`
+}
+
+// ForwardLog records the inbound faxes an AutoForwarder handled, so no fax is forwarded twice.
+type ForwardLog interface {
+	// Lookup returns the latest entry of an inbound fax by FaxFileName, or nil if there is none.
+	Lookup(fileName string) (*ForwardEntry, error)
+
+	// Add records an entry, replacing earlier entries of the same fax.
+	Add(*ForwardEntry) error
+}
+
+// FileForwardLog is a ForwardLog appending entries to a file at Path, one JSON object per line.
+type FileForwardLog struct {
+	Path string
+
+	mu      sync.Mutex
+	entries map[string]ForwardEntry
+	partial int64 // length of the file without a partially written last line, or -1
+}
+
+// Lookup implements ForwardLog.
+func (l *FileForwardLog) Lookup(fileName string) (*ForwardEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	e, ok := l.entries[fileName]
+	if !ok {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+// Add implements ForwardLog.
+func (l *FileForwardLog) Add(e *ForwardEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.load(); err != nil {
+		return err
+	}
+	if l.partial >= 0 {
+		if err := os.Truncate(l.Path, l.partial); err != nil {
+			return errors.Wrap(err, "failed to repair forward log")
+		}
+		l.partial = -1
+	}
+	if err := appendJSONLine(l.Path, e); err != nil {
+		return errors.Wrap(err, "failed to write forward log")
+	}
+	l.entries[e.FileName] = *e
+	return nil
+}
+
+// load reads the log once. A partially written last line, e.g., after a crash, is ignored
+// and truncated before the next entry is added.
+func (l *FileForwardLog) load() error {
+	if l.entries != nil {
+		return nil
+	}
+	entries := make(map[string]ForwardEntry)
+	l.partial = -1
+	b, err := ioutil.ReadFile(l.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to read forward log")
+	}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var e ForwardEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			if bytes.HasSuffix(b, sc.Bytes()) {
+				l.partial = int64(len(b) - len(sc.Bytes()))
+				break
+			}
+			return errors.Wrapf(err, "failed to decode forward log %s line %d", l.Path, line)
+		}
+		entries[e.FileName] = e
+	}
+	if err := sc.Err(); err != nil {
+		return errors.Wrap(err, "failed to read forward log")
+	}
+	l.entries = entries
+	return nil
+}
+
+// MemoryForwardLog is a ForwardLog kept in memory, e.g., for tests or short-lived processes.
+type MemoryForwardLog struct {
+	mu      sync.Mutex
+	entries map[string]ForwardEntry
+}
+
+// Lookup implements ForwardLog.
+func (l *MemoryForwardLog) Lookup(fileName string) (*ForwardEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, ok := l.entries[fileName]
+	if !ok {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+// Add implements ForwardLog.
+func (l *MemoryForwardLog) Add(e *ForwardEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.entries == nil {
+		l.entries = make(map[string]ForwardEntry)
+	}
+	l.entries[e.FileName] = *e
+	return nil
+}
+
+// AutoForwardRule forwards inbound faxes matching all of its conditions to To. Conditions
+// left empty match every fax.
+type AutoForwardRule struct {
+	// Any of the sender numbers, compared ignoring formatting
+	CallerID []string
+
+	// Any of the numbers the fax was received on
+	UserFaxNumber []string
+
+	// Recipients as accepted by ParseFaxNumber
+	To []string
+}
+
+// AutoForwarderOptions specify the sender and rules of an AutoForwarder.
+type AutoForwarderOptions struct {
+	// Sender of forwarded faxes, required
+	CallerID    int
+	SenderEmail string
+
+	// Rules are evaluated in order, and a fax is forwarded by the first matching rule
+	Rules []AutoForwardRule
+
+	// Fax numbers of the account and of any other account forwarding faxes to it. Faxes
+	// received from these numbers are never forwarded, which is what breaks forwarding
+	// loops. The CallerID is always included
+	OwnNumbers []string
+
+	// Log of handled faxes, defaults to a MemoryForwardLog
+	Log ForwardLog
+
+	// How long a fax whose ForwardFax request did not complete is looked for in the outbox
+	// before it is forwarded again, defaults to 10 minutes
+	Grace time.Duration
+
+	// Options of every forwarded fax. AccountCode is replaced by a code identifying the
+	// forward, which is how a fax forwarded just before a restart is found again
+	Options ForwardOptions
+}
+
+// AutoForwarder forwards inbound faxes by rules, e.g., as the handler of a FaxWatcher:
+//
+//	f := client.NewAutoForwarder(opts)
+//	watcher.Run(ctx, func(r srfax.FaxRecord) error {
+//		_, err := f.Forward(ctx, r)
+//		return err
+//	})
+//
+// Every forward is recorded in the log before ForwardFax is called. A fax whose request did
+// not complete is looked up in the outbox by its AccountCode, and only forwarded again if it
+// is still not found after the Grace period. Until then Forward returns an error, so the
+// watcher retries the fax.
+//
+// A fax is not forwarded if it was received from one of the OwnNumbers, and it is never
+// forwarded to its sender, to the number it was received on or to suppressed numbers.
+type AutoForwarder struct {
+	c    *Client
+	opts AutoForwarderOptions
+
+	mu       sync.Mutex
+	prepared bool
+	err      error
+	own      []string
+	rules    []AutoForwardRule
+}
+
+// NewAutoForwarder returns an AutoForwarder.
+func (c *Client) NewAutoForwarder(opts AutoForwarderOptions) *AutoForwarder {
+	if opts.Log == nil {
+		opts.Log = &MemoryForwardLog{}
+	}
+	if opts.Grace <= 0 {
+		opts.Grace = 10 * time.Minute
+	}
+	return &AutoForwarder{c: c, opts: opts}
+}
+
+// prepare validates the options once.
+func (f *AutoForwarder) prepare() error {
+	if f.prepared {
+		return f.err
+	}
+	f.prepared = true
+	if f.opts.CallerID == 0 || f.opts.SenderEmail == "" {
+		f.err = errors.New("auto forwarder requires CallerID and SenderEmail")
+		return f.err
+	}
+	own, err := ParseFaxNumbers(append([]string{strconv.Itoa(f.opts.CallerID)}, f.opts.OwnNumbers...)...)
+	if err != nil {
+		f.err = errors.Wrap(err, "invalid OwnNumbers")
+		return f.err
+	}
+	f.own = own
+	if err := f.opts.Options.validate(); err != nil {
+		f.err = errors.Wrap(err, "invalid Options")
+		return f.err
+	}
+	for i, rule := range f.opts.Rules {
+		to, err := ParseFaxNumbers(rule.To...)
+		if err != nil || len(to) == 0 {
+			f.err = errors.Errorf("rule %d requires valid To numbers: %v", i+1, err)
+			return f.err
+		}
+		rule.To = to
+		f.rules = append(f.rules, rule)
+	}
+	return nil
+}
+
+func (rule *AutoForwardRule) match(r *FaxRecord) bool {
+	return (len(rule.CallerID) == 0 || anyNumber(rule.CallerID, r.CallerID)) &&
+		(len(rule.UserFaxNumber) == 0 || anyNumber(rule.UserFaxNumber, r.UserFaxNumber))
+}
+
+// Forward forwards an inbound fax by the first matching rule and returns its log entry.
+// A nil entry is returned if no rule matches or the fax was already handled. Faxes blocked,
+// e.g., to prevent a loop or because all recipients are suppressed, are recorded with the
+// reason in Blocked and are not forwarded.
+//
+// Forward is safe for concurrent use, but the same fax must not be forwarded concurrently.
+func (f *AutoForwarder) Forward(ctx context.Context, r FaxRecord) (*ForwardEntry, error) {
+	f.mu.Lock()
+	err := f.prepare()
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if r.Direction != inbound {
+		return nil, errors.Errorf("fax %s is not inbound", r.FileName)
+	}
+
+	var rule *AutoForwardRule
+	for i := range f.rules {
+		if f.rules[i].match(&r) {
+			rule = &f.rules[i]
+			break
+		}
+	}
+	if rule == nil {
+		return nil, nil
+	}
+
+	entry, err := f.opts.Log.Lookup(r.FileName)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		if !entry.Pending {
+			return nil, nil
+		}
+		// the previous ForwardFax request may or may not have reached SRFax
+		found, err := f.resolve(ctx, entry)
+		if err != nil || found {
+			return nil, err
+		}
+		if time.Since(entry.Forwarded) < f.opts.Grace {
+			return nil, errors.Errorf("forward of %s is not in the outbox yet", r.FileName)
+		}
+	}
+
+	entry = &ForwardEntry{FileName: r.FileName, CallerID: r.CallerID, Forwarded: time.Now()}
+	switch {
+	case anyNumber(f.own, r.CallerID):
+		entry.Blocked = "received from our own number " + r.CallerID
+	default:
+		for _, to := range rule.To {
+			if !sameNumber(to, r.CallerID) && !sameNumber(to, r.UserFaxNumber) {
+				entry.To = append(entry.To, to)
+			}
+		}
+		if len(entry.To) == 0 {
+			entry.Blocked = "would forward to its sender or the number it was received on"
+		}
+	}
+	if entry.Blocked == "" {
+		allowed, suppressed, err := f.c.filterSuppressed(entry.To)
+		if err != nil {
+			return nil, err
+		}
+		entry.To = allowed
+		if len(allowed) == 0 {
+			entry.Blocked = (&SuppressedError{Numbers: suppressed}).Error()
+		}
+	}
+
+	// check the request before the entry is recorded as pending, so a fax that can never
+	// be forwarded is blocked instead of looked for in the outbox on every retry
+	cfg := ForwardCfg{
+		FaxFileName: r.FileName,
+		Direction:   inbound,
+		CallerID:    f.opts.CallerID,
+		SenderEmail: f.opts.SenderEmail,
+		FaxType:     single,
+		ToFaxNumber: entry.To,
+	}
+	if len(entry.To) > 1 {
+		cfg.FaxType = broadcast
+	}
+	if entry.Blocked == "" {
+		if err := cfg.validate(); err != nil {
+			entry.Blocked = err.Error()
+		}
+	}
+	if entry.Blocked != "" {
+		return entry, f.opts.Log.Add(entry)
+	}
+
+	sum := sha256.Sum256([]byte(r.FileName + "\x00" + strconv.FormatInt(entry.Forwarded.UnixNano(), 10)))
+	entry.AccountCode = hex.EncodeToString(sum[:])[:20]
+	entry.Pending = true
+	if err := f.opts.Log.Add(entry); err != nil {
+		return nil, err
+	}
+
+	opts := f.opts.Options
+	opts.AccountCode = entry.AccountCode
+	resp, err := f.c.forwardFax(ctx, cfg, opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to forward fax %s", r.FileName)
+	}
+	for _, s := range strings.Split(resp.Result, "|") {
+		if id, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			entry.IDs = append(entry.IDs, id)
+		}
+	}
+	entry.Pending = false
+	return entry, f.opts.Log.Add(entry)
+}
+
+// resolve looks up the outbound faxes of an incomplete entry by its AccountCode, and
+// completes the entry if they are found.
+func (f *AutoForwarder) resolve(ctx context.Context, entry *ForwardEntry) (bool, error) {
+	q := FaxQuery{Direction: outbound, Since: entry.Forwarded.Add(-time.Hour)}
+	records, err := f.c.Faxes(ctx, q).All()
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to look up forward of %s in the outbox", entry.FileName)
+	}
+	for _, r := range records {
+		if r.AccountCode == entry.AccountCode {
+			entry.IDs = append(entry.IDs, r.ID)
+		}
+	}
+	if len(entry.IDs) == 0 {
+		return false, nil
+	}
+	sort.Ints(entry.IDs)
+	entry.Pending = false
+	return true, f.opts.Log.Add(entry)
+}
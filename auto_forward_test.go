@@ -0,0 +1,221 @@
+package srfax
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAutoForwarder(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		forwards = make(map[string]int)
+		outbox   []map[string]interface{}
+		loseNext = true
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		var resp map[string]interface{}
+		switch req["action"] {
+		case actionForwardFax:
+			name := req["sFaxFileName"].(string)
+			forwards[name]++
+			id := strconv.Itoa(600 + len(outbox))
+			outbox = append(outbox, map[string]interface{}{
+				"FileName":    "20180101230101-8812-34_0|" + id,
+				"AccountCode": req["sAccountCode"],
+				"EpochTime":   strconv.FormatInt(time.Now().Unix(), 10),
+			})
+			if name == "b|4" && loseNext {
+				loseNext = false
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			resp = map[string]interface{}{"Status": "Success", "Result": id}
+		case actionGetFaxOutbox:
+			resp = map[string]interface{}{"Status": "Success", "Result": outbox}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	logPath := filepath.Join(dir, "forwarded.log")
+
+	client := &Client{account: account{9090, "abc"}, url: srv.URL}
+	newForwarder := func() *AutoForwarder {
+		return client.NewAutoForwarder(AutoForwarderOptions{
+			CallerID:    6135550000,
+			SenderEmail: "fax@example.com",
+			OwnNumbers:  []string{"613-555-0199"},
+			Rules: []AutoForwardRule{
+				{CallerID: []string{"6135550100", "6135550199"}, To: []string{"6135550199"}},
+				{UserFaxNumber: []string{"6135550001"}, To: []string{"6135550001"}},
+			},
+			Log: &FileForwardLog{Path: logPath},
+		})
+	}
+	ctx := context.Background()
+	f := newForwarder()
+
+	fax := func(name, from, to string) FaxRecord {
+		return FaxRecord{Direction: inbound, FileName: name, CallerID: from, UserFaxNumber: to}
+	}
+	e, err := f.Forward(ctx, fax("a|1", "6135550100", "6135550002"))
+	if err != nil || e == nil || len(e.IDs) != 1 || e.To[0] != "16135550199" {
+		t.Fatalf("want fax forwarded; got %+v %v", e, err)
+	}
+	if e, err := f.Forward(ctx, fax("a|1", "6135550100", "6135550002")); e != nil || err != nil {
+		t.Fatalf("want fax forwarded once; got %+v %v", e, err)
+	}
+	if e, err := f.Forward(ctx, fax("a|2", "6135550199", "6135550002")); err != nil || e == nil || e.Blocked == "" {
+		t.Fatalf("want fax from own number blocked; got %+v %v", e, err)
+	}
+	if e, err := f.Forward(ctx, fax("a|3", "6135550300", "6135550001")); err != nil || e == nil || e.Blocked == "" {
+		t.Fatalf("want fax to the receiving number blocked; got %+v %v", e, err)
+	}
+	if e, err := f.Forward(ctx, fax("a|5", "6135550300", "6135550002")); e != nil || err != nil {
+		t.Fatalf("want unmatched fax ignored; got %+v %v", e, err)
+	}
+	if _, err := f.Forward(ctx, fax("b|4", "6135550100", "6135550002")); err == nil {
+		t.Fatal("want error of the lost forward response")
+	}
+
+	// a crash left a partial line at the end of the log
+	lf, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lf.WriteString(`{"FileName": "c|`)
+	lf.Close()
+
+	f = newForwarder()
+	if e, err := f.Forward(ctx, fax("b|4", "6135550100", "6135550002")); e != nil || err != nil {
+		t.Fatalf("want lost forward found in the outbox; got %+v %v", e, err)
+	}
+	for _, name := range []string{"a|1", "a|2", "a|3"} {
+		if e, err := f.Forward(ctx, fax(name, "6135550100", "6135550002")); e != nil || err != nil {
+			t.Fatalf("want %s handled before the restart; got %+v %v", name, e, err)
+		}
+	}
+	for name, n := range forwards {
+		if n != 1 {
+			t.Errorf("want %s forwarded once; got %d", name, n)
+		}
+	}
+	if len(forwards) != 2 {
+		t.Fatalf("want 2 faxes forwarded; got %v", forwards)
+	}
+
+	log := &FileForwardLog{Path: logPath}
+	e, err = log.Lookup("b|4")
+	if err != nil || e == nil || e.Pending || len(e.IDs) != 1 || e.IDs[0] != 601 {
+		t.Fatalf("unexpected log entry %+v %v", e, err)
+	}
+}
+
+func TestAutoForwarderGrace(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		forwards int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		var resp map[string]interface{}
+		switch req["action"] {
+		case actionForwardFax:
+			// the first request never reaches SRFax
+			if forwards++; forwards == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			resp = map[string]interface{}{"Status": "Success", "Result": "700"}
+		case actionGetFaxOutbox:
+			resp = map[string]interface{}{"Status": "Success", "Result": []interface{}{}}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	list, err := NewFileSuppressionList(filepath.Join(dir, "suppressed.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Suppress("16135550300", "opt-out request"); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &Client{account: account{9090, "abc"}, url: srv.URL, suppression: list}
+	log := &MemoryForwardLog{}
+	opts := AutoForwarderOptions{
+		CallerID:    6135550000,
+		SenderEmail: "fax@example.com",
+		Rules: []AutoForwardRule{
+			{CallerID: []string{"6135550100"}, To: []string{"6135550199"}},
+			{To: []string{"6135550300"}},
+		},
+		Log: log,
+	}
+	f := client.NewAutoForwarder(opts)
+	ctx := context.Background()
+
+	fax := FaxRecord{Direction: inbound, FileName: "a|1", CallerID: "6135550100", UserFaxNumber: "6135550002"}
+	if _, err := f.Forward(ctx, fax); err == nil {
+		t.Fatal("want error of the lost forward request")
+	}
+	if _, err := f.Forward(ctx, fax); err == nil || forwards != 1 {
+		t.Fatalf("want no forward again within the grace period; got %v after %d forwards", err, forwards)
+	}
+
+	e, err := log.Lookup(fax.FileName)
+	if err != nil || e == nil || !e.Pending {
+		t.Fatalf("want pending log entry; got %+v %v", e, err)
+	}
+	e.Forwarded = e.Forwarded.Add(-11 * time.Minute)
+	log.Add(e)
+	if e, err := f.Forward(ctx, fax); err != nil || e == nil || len(e.IDs) != 1 || forwards != 2 {
+		t.Fatalf("want fax forwarded again after the grace period; got %+v %v after %d forwards", e, err, forwards)
+	}
+
+	// permanent failures are recorded without a forward request
+	e, err = f.Forward(ctx, FaxRecord{Direction: inbound, FileName: "b|2", CallerID: "6135550400"})
+	if err != nil || e == nil || e.Blocked == "" || e.Pending || forwards != 2 {
+		t.Fatalf("want fax to suppressed number blocked; got %+v %v after %d forwards", e, err, forwards)
+	}
+	e, err = f.Forward(ctx, FaxRecord{Direction: inbound, FileName: "", CallerID: "6135550100"})
+	if err != nil || e == nil || e.Blocked == "" || e.Pending || forwards != 2 {
+		t.Fatalf("want invalid forward blocked; got %+v %v after %d forwards", e, err, forwards)
+	}
+
+	opts.Options = ForwardOptions{Retries: 7}
+	if _, err := client.NewAutoForwarder(opts).Forward(ctx, fax); err == nil {
+		t.Fatal("want error of invalid Options")
+	}
+}
@@ -0,0 +1,327 @@
+package srfax
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// BlockList is a list of junk fax senders. Inbound faxes from blocked senders are removed
+// by a BlockEnforcer.
+//
+// Implementations must be safe for concurrent use.
+type BlockList interface {
+	// Blocked reports whether the sender of an inbound fax is on the list and the reason
+	// it was added.
+	Blocked(r *FaxRecord) (reason string, ok bool, err error)
+}
+
+// BlockEntry records why and when a sender was added to a FileBlockList.
+type BlockEntry struct {
+	Reason string    `json:"reason"`
+	Added  time.Time `json:"added"`
+}
+
+// FileBlockList is a BlockList backed by a JSON file, blocking senders by CallerID or by
+// RemoteID (CSID). The whole list is held in memory and the file is rewritten atomically
+// whenever it changes.
+type FileBlockList struct {
+	path string
+
+	mu      sync.RWMutex
+	entries blockEntries
+}
+
+type blockEntries struct {
+	// keyed by FaxNumber
+	CallerID map[string]BlockEntry `json:"caller_id"`
+	// keyed by lower case RemoteID
+	RemoteID map[string]BlockEntry `json:"remote_id"`
+}
+
+// NewFileBlockList loads a block list from path. A missing file is treated as an empty list
+// and is created, with its directory, when the first sender is blocked.
+func NewFileBlockList(path string) (*FileBlockList, error) {
+	l := &FileBlockList{path: path}
+	b, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to read block list")
+	}
+	if err == nil {
+		if err := json.Unmarshal(b, &l.entries); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode block list %s", path)
+		}
+	}
+	if l.entries.CallerID == nil {
+		l.entries.CallerID = make(map[string]BlockEntry)
+	}
+	if l.entries.RemoteID == nil {
+		l.entries.RemoteID = make(map[string]BlockEntry)
+	}
+	return l, nil
+}
+
+// callerIDKey normalizes a CallerID. Numbers that cannot be parsed, e.g., short codes, are
+// used as is.
+func callerIDKey(s string) string {
+	if n, err := ParseFaxNumber(s); err == nil {
+		return n.String()
+	}
+	return strings.TrimSpace(s)
+}
+
+func remoteIDKey(s string) string { return strings.ToLower(strings.TrimSpace(s)) }
+
+// Blocked implements BlockList. A blank CallerID or RemoteID never matches.
+func (l *FileBlockList) Blocked(r *FaxRecord) (string, bool, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if key := callerIDKey(r.CallerID); key != "" {
+		if e, ok := l.entries.CallerID[key]; ok {
+			return e.Reason, true, nil
+		}
+	}
+	if key := remoteIDKey(r.RemoteID); key != "" {
+		if e, ok := l.entries.RemoteID[key]; ok {
+			return e.Reason, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// BlockCallerID adds a sender number to the list, recording reason.
+func (l *FileBlockList) BlockCallerID(number, reason string) error {
+	key := callerIDKey(number)
+	if key == "" {
+		return errors.New("cannot block a blank CallerID")
+	}
+	return l.update(func(e *blockEntries) { e.CallerID[key] = BlockEntry{Reason: reason, Added: time.Now().UTC()} })
+}
+
+// BlockRemoteID adds a RemoteID to the list, recording reason. RemoteIDs are compared
+// ignoring case and surrounding space.
+func (l *FileBlockList) BlockRemoteID(remoteID, reason string) error {
+	key := remoteIDKey(remoteID)
+	if key == "" {
+		return errors.New("cannot block a blank RemoteID")
+	}
+	return l.update(func(e *blockEntries) { e.RemoteID[key] = BlockEntry{Reason: reason, Added: time.Now().UTC()} })
+}
+
+// Unblock removes a CallerID or RemoteID from the list.
+func (l *FileBlockList) Unblock(s string) error {
+	return l.update(func(e *blockEntries) {
+		delete(e.CallerID, callerIDKey(s))
+		delete(e.RemoteID, remoteIDKey(s))
+	})
+}
+
+// update applies fn to a copy of the entries and saves them.
+func (l *FileBlockList) update(fn func(*blockEntries)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries := l.copyEntries()
+	fn(&entries)
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return errors.Wrap(err, "failed to create block list directory")
+	}
+	if err := writeJSONFile(l.path, entries); err != nil {
+		return errors.Wrap(err, "failed to save block list")
+	}
+	l.entries = entries
+	return nil
+}
+
+func (l *FileBlockList) copyEntries() blockEntries {
+	out := blockEntries{
+		CallerID: make(map[string]BlockEntry, len(l.entries.CallerID)),
+		RemoteID: make(map[string]BlockEntry, len(l.entries.RemoteID)),
+	}
+	for k, v := range l.entries.CallerID {
+		out.CallerID[k] = v
+	}
+	for k, v := range l.entries.RemoteID {
+		out.RemoteID[k] = v
+	}
+	return out
+}
+
+// Entries returns copies of the blocked CallerIDs and RemoteIDs.
+func (l *FileBlockList) Entries() (callerIDs, remoteIDs map[string]BlockEntry) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	e := l.copyEntries()
+	return e.CallerID, e.RemoteID
+}
+
+// Audit actions of a BlockEnforcer.
+const (
+	AuditBlocked     = "blocked"
+	AuditQuarantined = "quarantined"
+	AuditDeleted     = "deleted"
+	AuditFailed      = "failed"
+)
+
+// BlockAuditEntry is a line of the audit log of a BlockEnforcer.
+type BlockAuditEntry struct {
+	Time   time.Time
+	Action string
+
+	// Inbound fax and sender
+	FileName string
+	ID       int
+	CallerID string
+	RemoteID string
+
+	// Why the sender is blocked
+	Reason string
+
+	// Quarantined files, relative to the quarantine directory
+	Files []string `json:",omitempty"`
+
+	// Why the action failed
+	Error string `json:",omitempty"`
+}
+
+// BlockEnforcerOptions specify the quarantine of a BlockEnforcer.
+type BlockEnforcerOptions struct {
+	// Directory blocked faxes are quarantined in, required
+	QuarantineDir string
+
+	// Retrieve blocked faxes into the quarantine before deleting them. Otherwise only their
+	// record is kept
+	Retrieve bool
+
+	// PDF or TIFF, defaults to PDF
+	FaxFormat string
+
+	// Audit log, one JSON object per line, defaults to audit.log in the QuarantineDir
+	AuditLog string
+}
+
+// BlockEnforcer removes inbound faxes from blocked senders, e.g., as the handler of a
+// FaxWatcher wrapping the handler of all other faxes:
+//
+//	enforcer := client.NewBlockEnforcer(list, srfax.BlockEnforcerOptions{QuarantineDir: "quarantine"})
+//	watcher.Run(ctx, enforcer.Handler(ctx, handle))
+//
+// A blocked fax is moved to the quarantine directory: its record is saved as JSON, and the
+// fax itself if Retrieve is set. It is then deleted with DeleteFax. Every step is written
+// to the audit log.
+type BlockEnforcer struct {
+	c    *Client
+	list BlockList
+	opts BlockEnforcerOptions
+
+	mu sync.Mutex // serializes audit log writes
+}
+
+// NewBlockEnforcer returns a BlockEnforcer for list.
+func (c *Client) NewBlockEnforcer(list BlockList, opts BlockEnforcerOptions) *BlockEnforcer {
+	if opts.FaxFormat == "" {
+		opts.FaxFormat = "PDF"
+	}
+	if opts.AuditLog == "" && opts.QuarantineDir != "" {
+		opts.AuditLog = filepath.Join(opts.QuarantineDir, "audit.log")
+	}
+	return &BlockEnforcer{c: c, list: list, opts: opts}
+}
+
+// Handler returns a FaxWatcher handler removing faxes from blocked senders and passing all
+// other faxes to next. next may be nil.
+func (b *BlockEnforcer) Handler(ctx context.Context, next func(FaxRecord) error) func(FaxRecord) error {
+	return func(r FaxRecord) error {
+		blocked, err := b.Enforce(ctx, r)
+		if err != nil || blocked || next == nil {
+			return err
+		}
+		return next(r)
+	}
+}
+
+// Enforce quarantines and deletes r if its sender is blocked, and reports whether it was.
+// A fax that could not be quarantined is not deleted, and an error is returned so it is
+// retried.
+func (b *BlockEnforcer) Enforce(ctx context.Context, r FaxRecord) (bool, error) {
+	if b.opts.QuarantineDir == "" {
+		return false, errors.New("block enforcer requires a QuarantineDir")
+	}
+	if r.Direction != inbound {
+		return false, nil
+	}
+	reason, ok, err := b.list.Blocked(&r)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to check block list")
+	}
+	if !ok {
+		return false, nil
+	}
+
+	entry := BlockAuditEntry{FileName: r.FileName, ID: r.ID, CallerID: r.CallerID, RemoteID: r.RemoteID, Reason: reason}
+	if err := b.audit(entry, AuditBlocked, nil); err != nil {
+		return true, err
+	}
+
+	files, err := b.quarantine(ctx, r)
+	entry.Files = files
+	if err != nil {
+		b.audit(entry, AuditFailed, err)
+		return true, errors.Wrapf(err, "failed to quarantine fax %s", r.FileName)
+	}
+	if err := b.audit(entry, AuditQuarantined, nil); err != nil {
+		return true, err
+	}
+	entry.Files = nil
+
+	if _, err := b.c.deleteFax(ctx, []string{r.FileName}, inbound); err != nil {
+		b.audit(entry, AuditFailed, err)
+		return true, errors.Wrapf(err, "failed to delete blocked fax %s", r.FileName)
+	}
+	return true, b.audit(entry, AuditDeleted, nil)
+}
+
+// quarantine saves the record of r, and the fax if Retrieve is set, and returns the names
+// of the saved files.
+func (b *BlockEnforcer) quarantine(ctx context.Context, r FaxRecord) ([]string, error) {
+	if err := os.MkdirAll(b.opts.QuarantineDir, 0755); err != nil {
+		return nil, err
+	}
+	name := downloadName(r, b.opts.FaxFormat)
+	var files []string
+	if b.opts.Retrieve {
+		path := filepath.Join(b.opts.QuarantineDir, name)
+		if _, err := b.c.RetrieveFaxToFile(ctx, r.Ref(), path, RetrieveOptions{FaxFormat: b.opts.FaxFormat}); err != nil {
+			return nil, err
+		}
+		files = append(files, name)
+	}
+	if err := writeJSONFile(filepath.Join(b.opts.QuarantineDir, name+".json"), r); err != nil {
+		return files, err
+	}
+	return append(files, name+".json"), nil
+}
+
+// audit writes an entry with action to the audit log.
+func (b *BlockEnforcer) audit(entry BlockAuditEntry, action string, err error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry.Time = time.Now().UTC()
+	entry.Action = action
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if err := os.MkdirAll(filepath.Dir(b.opts.AuditLog), 0755); err != nil {
+		return errors.Wrap(err, "failed to write audit log")
+	}
+	if err := appendJSONLine(b.opts.AuditLog, entry); err != nil {
+		return errors.Wrap(err, "failed to write audit log")
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package srfax
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestFileBlockList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "srfax", "blocked.json")
+
+	l, err := NewFileBlockList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+		t.Fatalf("want directory created on the first change only; got %v", err)
+	}
+	if err := l.BlockCallerID("(613) 555-0100", "junk"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.BlockRemoteID(" Promo Corp ", "ads"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.BlockCallerID("", "blank"); err == nil {
+		t.Fatal("want error blocking a blank CallerID")
+	}
+
+	l, err = NewFileBlockList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt := []struct {
+		r      FaxRecord
+		reason string
+	}{
+		{FaxRecord{CallerID: "6135550100"}, "junk"},
+		{FaxRecord{CallerID: "16135550100", RemoteID: "promo corp"}, "junk"},
+		{FaxRecord{CallerID: "6135550101", RemoteID: "PROMO CORP"}, "ads"},
+		{FaxRecord{CallerID: "6135550101", RemoteID: "Promo"}, ""},
+		{FaxRecord{}, ""},
+	}
+	for i, tc := range tt {
+		reason, ok, err := l.Blocked(&tc.r)
+		if err != nil || ok != (tc.reason != "") || reason != tc.reason {
+			t.Errorf("%d: want %q; got %q %v %v", i, tc.reason, reason, ok, err)
+		}
+	}
+
+	if err := l.Unblock("613 555 0100"); err != nil {
+		t.Fatal(err)
+	}
+	callerIDs, remoteIDs := l.Entries()
+	if len(callerIDs) != 0 || len(remoteIDs) != 1 {
+		t.Fatalf("unexpected entries %v %v", callerIDs, remoteIDs)
+	}
+}
+
+func TestBlockEnforcer(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		deleted []string
+		fail    = true
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		var resp map[string]interface{}
+		switch req["action"] {
+		case actionRetrieveFax:
+			if fail {
+				fail = false
+				resp = map[string]interface{}{"Status": "Failed", "Result": "Temporarily unavailable"}
+				break
+			}
+			resp = map[string]interface{}{"Status": "Success", "Result": base64.StdEncoding.EncodeToString([]byte("%PDF-1.4"))}
+		case actionDeleteFax:
+			deleted = append(deleted, req["sFaxFileName_0"].(string))
+			resp = map[string]interface{}{"Status": "Success", "Result": ""}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	list, err := NewFileBlockList(filepath.Join(dir, "blocked.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := list.BlockCallerID("6135550100", "junk"); err != nil {
+		t.Fatal(err)
+	}
+
+	client := &Client{account: account{9090, "abc"}, url: srv.URL}
+	quarantine := filepath.Join(dir, "quarantine")
+	enforcer := client.NewBlockEnforcer(list, BlockEnforcerOptions{QuarantineDir: quarantine, Retrieve: true})
+	var passed []string
+	handle := enforcer.Handler(context.Background(), func(r FaxRecord) error {
+		passed = append(passed, r.FileName)
+		return nil
+	})
+
+	junk := FaxRecord{Direction: inbound, FileName: "20180101230101-8812-34_0|100", ID: 100, CallerID: "6135550100"}
+	if err := handle(FaxRecord{Direction: inbound, FileName: "20180101230101-8812-34_0|101", ID: 101, CallerID: "6135550101"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := handle(junk); err == nil {
+		t.Fatal("want error of the failed retrieval")
+	}
+	if len(deleted) != 0 {
+		t.Fatal("want fax kept when it could not be quarantined")
+	}
+	if err := handle(junk); err != nil {
+		t.Fatal(err)
+	}
+	if len(passed) != 1 || len(deleted) != 1 || deleted[0] != junk.FileName {
+		t.Fatalf("want junk deleted and other fax passed on; got %v %v", deleted, passed)
+	}
+	name := "IN_20180101-230101_100_6135550100.pdf"
+	for _, f := range []string{name, name + ".json"} {
+		if _, err := os.Stat(filepath.Join(quarantine, f)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	f, err := os.Open(filepath.Join(quarantine, "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var actions []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e BlockAuditEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Reason != "junk" || e.ID != 100 {
+			t.Fatalf("unexpected audit entry %+v", e)
+		}
+		actions = append(actions, e.Action)
+	}
+	want := []string{AuditBlocked, AuditFailed, AuditBlocked, AuditQuarantined, AuditDeleted}
+	if len(actions) != len(want) {
+		t.Fatalf("want audit %v; got %v", want, actions)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Fatalf("want audit %v; got %v", want, actions)
+		}
+	}
+}
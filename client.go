@@ -15,6 +15,9 @@ type ClientCfg struct {
 
 	// access_pwd
 	Pwd string
+
+	// Optional do-not-fax list consulted before sending, see SuppressionList
+	Suppression SuppressionList
 }
 
 func (cfg ClientCfg) validate() error {
@@ -36,13 +39,14 @@ func NewClient(cfg ClientCfg) (*Client, error) {
 	// apiURL is the SRFax API url.
 	apiURL := "https://www.srfax.com/SRF_SecWebSvc.php"
 
-	return &Client{account{AccessID: cfg.ID, AccessPwd: cfg.Pwd}, apiURL}, nil
+	return &Client{account: account{AccessID: cfg.ID, AccessPwd: cfg.Pwd}, url: apiURL, suppression: cfg.Suppression}, nil
 }
 
 // Client is an SRFax client.
 type Client struct {
 	account
-	url string
+	url         string
+	suppression SuppressionList
 }
 
 type account struct {
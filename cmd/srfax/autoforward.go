@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mfridman/srfax"
+)
+
+var cmdAutoForward = &command{
+	usage:   "-to number [flags]",
+	summary: "forward new inbound faxes to other numbers until interrupted",
+}
+
+func init() {
+	cmdAutoForward.run = runAutoForward
+	commands["autoforward"] = cmdAutoForward
+}
+
+func runAutoForward(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("autoforward", cmdAutoForward)
+	to, from, receivedOn, own := &listFlag{}, &listFlag{}, &listFlag{}, &listFlag{}
+	fs.Var(to, "to", "recipient fax number, may be repeated or comma separated")
+	fs.Var(from, "from", "only forward faxes from this number, may be repeated or comma separated")
+	fs.Var(receivedOn, "received-on", "only forward faxes received on this number, may be repeated or comma separated")
+	fs.Var(own, "own", "our own fax numbers, faxes from them are never forwarded to prevent loops")
+	callerID := fs.Int("caller-id", e.cfg.CallerID, "sender fax number, 10 digits")
+	email := fs.String("email", e.cfg.SenderEmail, "sender email address")
+	logPath := fs.String("log", "", "file recording forwarded faxes, to never forward a fax twice across restarts")
+	state := fs.String("state", "", "file remembering which inbound faxes were handled, to continue after a restart")
+	interval := fs.Duration("interval", time.Minute, "delay between polls")
+	grace := fs.Duration("grace", 10*time.Minute, "look for a fax in the outbox this long before forwarding it again when its forward request failed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := e.ready(); err != nil {
+		return err
+	}
+	if len(*to) == 0 || fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	opts := srfax.AutoForwarderOptions{
+		CallerID:    *callerID,
+		SenderEmail: *email,
+		Rules:       []srfax.AutoForwardRule{{CallerID: *from, UserFaxNumber: *receivedOn, To: *to}},
+		OwnNumbers:  *own,
+		Grace:       *grace,
+	}
+	if *logPath != "" {
+		opts.Log = &srfax.FileForwardLog{Path: *logPath}
+	}
+	f := e.client.NewAutoForwarder(opts)
+
+	var store srfax.CursorStore = &srfax.MemoryCursorStore{}
+	if *state != "" {
+		store = &srfax.FileCursorStore{Path: *state}
+	}
+	w := &watcher{e: e, name: "autoforward"}
+	fw := e.client.NewFaxWatcher(store, srfax.FaxWatcherOptions{Interval: *interval, OnError: func(err error) { w.errorf("%v", err) }})
+	fw.Run(ctx, func(r srfax.FaxRecord) error {
+		entry, err := f.Forward(ctx, r)
+		if err != nil || entry == nil {
+			return err
+		}
+		line := fmt.Sprintf("IN   %d from %s: ", r.ID, orUnknown(r.CallerID))
+		if entry.Blocked != "" {
+			line += "not forwarded, " + entry.Blocked
+		} else {
+			ids := make([]string, len(entry.IDs))
+			for i, id := range entry.IDs {
+				ids[i] = strconv.Itoa(id)
+			}
+			line += "forwarded to " + strings.Join(entry.To, ",") + " as " + strings.Join(ids, ",")
+		}
+		w.emit(watchEvent{Type: "forwarded", Time: time.Now(), Record: &r, Forward: entry}, line)
+		return nil
+	})
+	return nil
+}
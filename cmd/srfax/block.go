@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mfridman/srfax"
+	"github.com/pkg/errors"
+)
+
+var cmdBlock = &command{
+	usage:   "add|remove|list|enforce [flags] [number or RemoteID...]",
+	summary: "manage blocked senders, and delete their faxes until interrupted with enforce",
+}
+
+func init() {
+	cmdBlock.run = runBlock
+	commands["block"] = cmdBlock
+}
+
+// defaultBlockList returns srfax/blocklist.json in the user config directory.
+func defaultBlockList() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "blocklist.json"
+	}
+	return filepath.Join(dir, "srfax", "blocklist.json")
+}
+
+func runBlock(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("block", cmdBlock)
+	file := fs.String("file", defaultBlockList(), "block list file")
+	remoteID := fs.Bool("remote-id", false, "add: block RemoteIDs instead of numbers")
+	reason := fs.String("reason", "junk fax", "add: reason recorded with blocked senders")
+	quarantine := fs.String("quarantine", "quarantine", "enforce: directory blocked faxes are moved to, with the audit log")
+	retrieve := fs.Bool("retrieve", false, "enforce: retrieve blocked faxes into the quarantine before deleting them")
+	format := fs.String("format", "PDF", "enforce: PDF or TIFF, format of retrieved faxes")
+	state := fs.String("state", "", "enforce: file remembering which faxes were checked, to continue after a restart")
+	interval := fs.Duration("interval", time.Minute, "enforce: delay between polls")
+	if len(args) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	action := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	list, err := srfax.NewFileBlockList(*file)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "add":
+		if fs.NArg() == 0 {
+			fs.Usage()
+			return errUsage
+		}
+		for _, s := range fs.Args() {
+			if *remoteID {
+				err = list.BlockRemoteID(s, *reason)
+			} else {
+				err = list.BlockCallerID(s, *reason)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case "remove":
+		if fs.NArg() == 0 {
+			fs.Usage()
+			return errUsage
+		}
+		for _, s := range fs.Args() {
+			if err := list.Unblock(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "list":
+		callerIDs, remoteIDs := list.Entries()
+		var rows [][]string
+		for kind, entries := range map[string]map[string]srfax.BlockEntry{"CallerID": callerIDs, "RemoteID": remoteIDs} {
+			for k, v := range entries {
+				rows = append(rows, []string{kind, k, v.Reason, v.Added.Local().Format("2006-01-02 15:04")})
+			}
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i][0]+rows[i][1] < rows[j][0]+rows[j][1] })
+		return e.print(map[string]interface{}{"CallerID": callerIDs, "RemoteID": remoteIDs}, []string{"TYPE", "SENDER", "REASON", "ADDED"}, rows)
+	case "enforce":
+	default:
+		fs.Usage()
+		return errUsage
+	}
+
+	if err := e.ready(); err != nil {
+		return err
+	}
+	if *format != "PDF" && *format != "TIFF" {
+		return errors.New("-format must be PDF or TIFF")
+	}
+	enforcer := e.client.NewBlockEnforcer(list, srfax.BlockEnforcerOptions{QuarantineDir: *quarantine, Retrieve: *retrieve, FaxFormat: *format})
+	var store srfax.CursorStore = &srfax.MemoryCursorStore{}
+	if *state != "" {
+		store = &srfax.FileCursorStore{Path: *state}
+	}
+	w := &watcher{e: e, name: "block"}
+	fw := e.client.NewFaxWatcher(store, srfax.FaxWatcherOptions{Interval: *interval, OnError: func(err error) { w.errorf("%v", err) }})
+	fw.Run(ctx, func(r srfax.FaxRecord) error {
+		blocked, err := enforcer.Enforce(ctx, r)
+		if err != nil || !blocked {
+			return err
+		}
+		w.emit(watchEvent{Type: "blocked", Time: time.Now(), Record: &r}, fmt.Sprintf("IN   %d from %s: quarantined and deleted", r.ID, orUnknown(r.CallerID)))
+		return nil
+	})
+	return nil
+}
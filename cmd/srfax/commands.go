@@ -0,0 +1,532 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mfridman/srfax"
+	"github.com/pkg/errors"
+)
+
+var cmdSend = &command{
+	usage:   "-to number [-to number ...] [flags] [file ...]",
+	summary: "queue files, or a cover page only, for delivery",
+}
+
+var cmdInbox = &command{
+	usage:   "[flags]",
+	summary: "list received faxes",
+}
+
+var cmdOutbox = &command{
+	usage:   "[flags]",
+	summary: "list sent faxes",
+}
+
+var cmdGet = &command{
+	usage:   "[flags] ident",
+	summary: "retrieve a fax by FaxDetailsID or FaxFileName to a file",
+}
+
+var cmdStatus = &command{
+	usage:   "id [id ...]",
+	summary: "show the status of sent faxes",
+}
+
+var cmdForward = &command{
+	usage:   "-to number [-to number ...] [flags] ident",
+	summary: "forward a received or sent fax to other numbers",
+}
+
+var cmdStop = &command{
+	usage:   "id [id ...]",
+	summary: "stop queued faxes that have not been sent yet",
+}
+
+var cmdDelete = &command{
+	usage:   "[flags] ident [ident ...]",
+	summary: "delete received or sent faxes",
+}
+
+var cmdMarkRead = &command{
+	usage:   "[flags] ident [ident ...]",
+	summary: "mark faxes as read",
+}
+
+var cmdMarkUnread = &command{
+	usage:   "[flags] ident [ident ...]",
+	summary: "mark faxes as unread",
+}
+
+var cmdUsage = &command{
+	usage:   "[flags]",
+	summary: "show the number of faxes and pages used",
+}
+
+// Commands refer to themselves for their usage, so run is assigned in init to avoid an
+// initialization cycle.
+func init() {
+	cmdSend.run = runSend
+	cmdInbox.run = func(ctx context.Context, e *env, args []string) error {
+		return runList(ctx, e, "inbox", cmdInbox, args)
+	}
+	cmdOutbox.run = func(ctx context.Context, e *env, args []string) error {
+		return runList(ctx, e, "outbox", cmdOutbox, args)
+	}
+	cmdGet.run = runGet
+	cmdStatus.run = runStatus
+	cmdForward.run = runForward
+	cmdStop.run = runStop
+	cmdDelete.run = runDelete
+	cmdMarkRead.run = func(ctx context.Context, e *env, args []string) error {
+		return runMark(e, "mark-read", cmdMarkRead, "Y", args)
+	}
+	cmdMarkUnread.run = func(ctx context.Context, e *env, args []string) error {
+		return runMark(e, "mark-unread", cmdMarkUnread, "N", args)
+	}
+	cmdUsage.run = runUsage
+}
+
+// parseDate parses a YYYY-MM-DD flag value in local time, blank yields the zero time.
+func parseDate(name, s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, errors.Errorf("-%s must have format YYYY-MM-DD", name)
+	}
+	return t, nil
+}
+
+// direction normalizes a -dir flag value.
+func direction(s string) (string, error) {
+	s = strings.ToUpper(s)
+	if s != "IN" && s != "OUT" {
+		return "", errors.New("-dir must be IN or OUT")
+	}
+	return s, nil
+}
+
+// faxType returns SINGLE or BROADCAST for the number of recipients.
+func faxType(n int) string {
+	if n > 1 {
+		return "BROADCAST"
+	}
+	return "SINGLE"
+}
+
+func runSend(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("send", cmdSend)
+	to := &listFlag{}
+	fs.Var(to, "to", "recipient fax number, may be repeated or comma separated")
+	callerID := fs.Int("caller-id", e.cfg.CallerID, "sender fax number, 10 digits")
+	email := fs.String("email", e.cfg.SenderEmail, "sender email address")
+	var opts srfax.QueueOptions
+	fs.StringVar(&opts.CoverPage, "cover", "", "cover page on file: Basic, Standard, Company or Personal")
+	fs.StringVar(&opts.CPFromName, "cover-from", "", "sender name on the cover page")
+	fs.StringVar(&opts.CPToName, "cover-to", "", "recipient name on the cover page")
+	fs.StringVar(&opts.CPOrganization, "cover-org", "", "recipient organization on the cover page")
+	fs.StringVar(&opts.CPSubject, "subject", "", "subject, saved with the fax even without a cover page")
+	fs.StringVar(&opts.CPComments, "comments", "", "comments on the cover page")
+	fs.StringVar(&opts.QueueFaxDate, "date", "", "schedule for a future date, YYYY-MM-DD")
+	fs.StringVar(&opts.QueueFaxTime, "time", "", "schedule for a time on -date, HH:MM in the account's timezone")
+	fs.StringVar(&opts.AccountCode, "account-code", "", "internal reference number, up to 20 characters")
+	fs.StringVar(&opts.FaxFromHeader, "header", "", "from on the fax header line, up to 30 characters")
+	fs.StringVar(&opts.NotifyURL, "notify", "", "URL SRFax POSTs the status record to when the fax completes")
+	fs.IntVar(&opts.Retries, "retries", 0, "number of retries if busy or failed, 0 to 6")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := e.ready(); err != nil {
+		return err
+	}
+	if len(*to) == 0 || (fs.NArg() == 0 && opts.CoverPage == "") {
+		fs.Usage()
+		return errUsage
+	}
+	numbers, err := srfax.ParseFaxNumbers(*to...)
+	if err != nil {
+		return err
+	}
+	files := make([]srfax.File, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, srfax.File{Name: filepath.Base(path), Content: base64.StdEncoding.EncodeToString(b)})
+	}
+	cfg := srfax.QueueCfg{CallerID: *callerID, SenderEmail: *email, FaxType: faxType(len(numbers)), ToFaxNumber: numbers}
+
+	resp, err := e.client.QueueFax(files, cfg, opts)
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, id := range strings.Split(resp.Result, "|") {
+		rows = append(rows, []string{id})
+	}
+	return e.print(resp, []string{"ID"}, rows)
+}
+
+func runList(ctx context.Context, e *env, name string, cmd *command, args []string) error {
+	fs := e.newFlagSet(name, cmd)
+	since := fs.String("since", "", "only faxes on or after this date, YYYY-MM-DD")
+	until := fs.String("until", "", "only faxes before this date, YYYY-MM-DD")
+	subUsers := fs.Bool("subusers", false, "include faxes of sub users")
+	number := fs.String("number", "", "only faxes from (inbox) or to (outbox) this number")
+	minPages := fs.Int("min-pages", 0, "only faxes with at least this many pages")
+	var unread, read *bool
+	if name == "inbox" {
+		unread = fs.Bool("unread", false, "only unread faxes")
+		read = fs.Bool("read", false, "only read faxes")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := e.ready(); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	q := srfax.FaxQuery{Direction: "IN", IncludeSubUsers: *subUsers, MinPages: *minPages}
+	var err error
+	if q.Since, err = parseDate("since", *since); err != nil {
+		return err
+	}
+	if q.Until, err = parseDate("until", *until); err != nil {
+		return err
+	}
+	if !q.Until.IsZero() && q.Since.IsZero() {
+		return errors.New("-until requires -since")
+	}
+	if name == "inbox" {
+		q.CallerID = *number
+		switch {
+		case *unread && *read:
+			return errors.New("-unread and -read are mutually exclusive")
+		case *unread:
+			q.ViewedStatus = "UNREAD"
+		case *read:
+			q.ViewedStatus = "READ"
+		}
+	} else {
+		q.Direction = "OUT"
+		q.ToFaxNumber = *number
+	}
+
+	records, err := e.client.Faxes(ctx, q).All()
+	if err != nil {
+		return err
+	}
+	if records == nil {
+		records = []srfax.FaxRecord{}
+	}
+	var (
+		header = []string{"ID", "DATE", "FROM", "PAGES", "STATUS", "VIEWED"}
+		rows   [][]string
+	)
+	if name == "outbox" {
+		header = []string{"ID", "DATE", "TO", "PAGES", "STATUS", "ERROR"}
+	}
+	for _, r := range records {
+		row := []string{strconv.Itoa(r.ID), r.Time.Format("2006-01-02 15:04"), r.CallerID, strconv.Itoa(r.Pages), r.Status, r.ViewedStatus}
+		if name == "outbox" {
+			row[2], row[5] = r.ToFaxNumber, r.ErrorCode
+		}
+		rows = append(rows, row)
+	}
+	return e.print(records, header, rows)
+}
+
+func runGet(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("get", cmdGet)
+	dir := fs.String("dir", "IN", "IN or OUT for a received or sent fax")
+	format := fs.String("format", "PDF", "PDF or TIFF")
+	out := fs.String("o", "", "output file, - for stdout, defaults to <id>.pdf or <id>.tif")
+	verify := fs.Bool("verify", false, "check the document is well-formed, retrieving it again if not")
+	markRead := fs.Bool("mark-read", false, "mark the fax as read once retrieved")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := e.ready(); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	d, err := direction(*dir)
+	if err != nil {
+		return err
+	}
+	*format = strings.ToUpper(*format)
+	if *format != "PDF" && *format != "TIFF" {
+		return errors.New("-format must be PDF or TIFF")
+	}
+	ref := srfax.FaxRef{Ident: fs.Arg(0), Direction: d}
+	opts := srfax.RetrieveOptions{FaxFormat: *format}
+	if *markRead {
+		opts.MarkAsViewed = "Y"
+	}
+
+	if *out == "-" {
+		_, err := e.client.RetrieveFaxTo(ctx, ref, e.stdout, opts)
+		return err
+	}
+	if *out == "" {
+		id, err := srfax.IDFromName(ref.Ident)
+		if err != nil {
+			return err
+		}
+		*out = strconv.Itoa(id) + ".pdf"
+		if *format == "TIFF" {
+			*out = strconv.Itoa(id) + ".tif"
+		}
+	}
+
+	result := &srfax.Integrity{}
+	if *verify {
+		result, err = e.client.RetrieveFaxVerified(ctx, ref, *out, srfax.VerifyOptions{}, opts)
+	} else {
+		result.Size, err = e.client.RetrieveFaxToFile(ctx, ref, *out, opts)
+		result.Format = *format
+	}
+	if err != nil {
+		return err
+	}
+	v := struct {
+		Path string
+		srfax.Integrity
+	}{*out, *result}
+	row := []string{*out, strconv.FormatInt(result.Size, 10), result.Format, strconv.Itoa(result.Pages), result.SHA256}
+	return e.print(v, []string{"PATH", "SIZE", "FORMAT", "PAGES", "SHA256"}, [][]string{row})
+}
+
+func runStatus(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("status", cmdStatus)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := e.ready(); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	resp, err := e.client.GetMulFaxStatus(fs.Args())
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, r := range resp.Result {
+		id, _ := srfax.IDFromName(r.FileName)
+		rows = append(rows, []string{strconv.Itoa(id), r.SentStatus, r.DateQueued, r.DateSent, r.ToFaxNumber, r.Pages, r.ErrorCode})
+	}
+	return e.print(resp.Result, []string{"ID", "STATUS", "QUEUED", "SENT", "TO", "PAGES", "ERROR"}, rows)
+}
+
+func runForward(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("forward", cmdForward)
+	to := &listFlag{}
+	fs.Var(to, "to", "recipient fax number, may be repeated or comma separated")
+	callerID := fs.Int("caller-id", e.cfg.CallerID, "sender fax number, 10 digits")
+	email := fs.String("email", e.cfg.SenderEmail, "sender email address")
+	dir := fs.String("dir", "IN", "IN or OUT for a received or sent fax")
+	var opts srfax.ForwardOptions
+	fs.StringVar(&opts.AccountCode, "account-code", "", "internal reference number, up to 20 characters")
+	fs.StringVar(&opts.NotifyURL, "notify", "", "URL SRFax POSTs the status record to when the fax completes")
+	fs.StringVar(&opts.QueueFaxDate, "date", "", "schedule for a future date, YYYY-MM-DD")
+	fs.StringVar(&opts.QueueFaxTime, "time", "", "schedule for a time on -date, HH:MM in the account's timezone")
+	fs.IntVar(&opts.Retries, "retries", 0, "number of retries if busy or failed, 0 to 6")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := e.ready(); err != nil {
+		return err
+	}
+	if len(*to) == 0 || fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	d, err := direction(*dir)
+	if err != nil {
+		return err
+	}
+	numbers, err := srfax.ParseFaxNumbers(*to...)
+	if err != nil {
+		return err
+	}
+	cfg := srfax.ForwardCfg{Direction: d, CallerID: *callerID, SenderEmail: *email, FaxType: faxType(len(numbers)), ToFaxNumber: numbers}
+	if ident := fs.Arg(0); strings.Contains(ident, "|") {
+		cfg.FaxFileName = ident
+	} else {
+		cfg.FaxDetailsID = ident
+	}
+
+	resp, err := e.client.ForwardFax(cfg, opts)
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, id := range strings.Split(resp.Result, "|") {
+		rows = append(rows, []string{id})
+	}
+	return e.print(resp, []string{"ID"}, rows)
+}
+
+// result is the outcome of an operation on a single fax.
+type result struct {
+	Ident  string
+	Result string
+}
+
+func printResults(e *env, results []result) error {
+	var rows [][]string
+	for _, r := range results {
+		rows = append(rows, []string{r.Ident, r.Result})
+	}
+	return e.print(results, []string{"FAX", "RESULT"}, rows)
+}
+
+func runStop(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("stop", cmdStop)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := e.ready(); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	var results []result
+	for _, arg := range fs.Args() {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return errors.Errorf("invalid FaxDetailsID %q", arg)
+		}
+		resp, err := e.client.StopFax(id)
+		if err != nil {
+			return errors.Wrapf(err, "fax %d", id)
+		}
+		results = append(results, result{arg, resp.Result})
+	}
+	return printResults(e, results)
+}
+
+func runDelete(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("delete", cmdDelete)
+	dir := fs.String("dir", "IN", "IN or OUT for received or sent faxes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := e.ready(); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	d, err := direction(*dir)
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.DeleteFax(fs.Args(), d)
+	if err != nil {
+		return err
+	}
+	return printResults(e, []result{{strings.Join(fs.Args(), ","), resp.Result}})
+}
+
+func runMark(e *env, name string, cmd *command, viewed string, args []string) error {
+	fs := e.newFlagSet(name, cmd)
+	dir := fs.String("dir", "IN", "IN or OUT for received or sent faxes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := e.ready(); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	d, err := direction(*dir)
+	if err != nil {
+		return err
+	}
+	var results []result
+	for _, ident := range fs.Args() {
+		cfg := srfax.ViewedStatusCfg{Direction: d, MarkAsViewed: viewed}
+		if strings.Contains(ident, "|") {
+			cfg.FaxFileName = ident
+		} else if cfg.FaxDetailsID, err = strconv.Atoi(ident); err != nil {
+			return errors.Errorf("invalid fax identifier %q", ident)
+		}
+		resp, err := e.client.UpdateViewedStatus(cfg)
+		if err != nil {
+			return errors.Wrapf(err, "fax %s", ident)
+		}
+		results = append(results, result{ident, resp.Result})
+	}
+	return printResults(e, results)
+}
+
+func runUsage(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("usage", cmdUsage)
+	since := fs.String("since", "", "start date, YYYY-MM-DD")
+	until := fs.String("until", "", "end date, YYYY-MM-DD, defaults to today")
+	subUsers := fs.Bool("subusers", false, "include sub users")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := e.ready(); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+	var opts srfax.FaxUsageOptions
+	if *subUsers {
+		opts.IncludeSubUsers = "Y"
+	}
+	start, err := parseDate("since", *since)
+	if err != nil {
+		return err
+	}
+	end, err := parseDate("until", *until)
+	if err != nil {
+		return err
+	}
+	if !start.IsZero() {
+		if end.IsZero() {
+			end = time.Now()
+		}
+		opts.Period, opts.StartDate, opts.EndDate = "RANGE", start.Format("20060102"), end.Format("20060102")
+	} else if !end.IsZero() {
+		return errors.New("-until requires -since")
+	}
+
+	resp, err := e.client.GetFaxUsage(opts)
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, r := range resp.Result {
+		rows = append(rows, []string{r.Period, strconv.Itoa(r.UserID), strconv.Itoa(r.SubUserID), r.BillingNumber, strconv.Itoa(r.NumberOfFaxes), strconv.Itoa(r.NumberOfPages)})
+	}
+	return e.print(resp.Result, []string{"PERIOD", "USER", "SUBUSER", "BILLING", "FAXES", "PAGES"}, rows)
+}
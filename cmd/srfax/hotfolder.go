@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mfridman/srfax"
+)
+
+var cmdHotFolder = &command{
+	usage:   "[flags] <dir>",
+	summary: "fax documents dropped into a directory until interrupted",
+}
+
+func init() {
+	cmdHotFolder.run = runHotFolder
+	commands["hotfolder"] = cmdHotFolder
+}
+
+func runHotFolder(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("hotfolder", cmdHotFolder)
+	callerID := fs.Int("caller-id", e.cfg.CallerID, "sender fax number, 10 digits")
+	email := fs.String("email", e.cfg.SenderEmail, "sender email address")
+	interval := fs.Duration("interval", 30*time.Second, "delay between polls")
+	settle := fs.Duration("settle", 10*time.Second, "leave files modified within this period, they may still be written")
+	grace := fs.Duration("grace", 10*time.Minute, "look for a document in the outbox this long before sending it again when its queue request failed")
+	var opts srfax.QueueOptions
+	fs.StringVar(&opts.CoverPage, "cover", "", "cover page on file: Basic, Standard, Company or Personal")
+	fs.StringVar(&opts.CPFromName, "cover-from", "", "sender name on the cover page")
+	fs.StringVar(&opts.FaxFromHeader, "header", "", "from on the fax header line, up to 30 characters")
+	fs.StringVar(&opts.NotifyURL, "notify", "", "URL SRFax POSTs the status record to when a fax completes")
+	fs.IntVar(&opts.Retries, "retries", 0, "number of retries if busy or failed, 0 to 6")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := e.ready(); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	w := &watcher{e: e, name: "hotfolder"}
+	h := e.client.NewHotFolder(fs.Arg(0), srfax.HotFolderOptions{
+		CallerID:    *callerID,
+		SenderEmail: *email,
+		Queue:       opts,
+		Interval:    *interval,
+		Settle:      *settle,
+		Grace:       *grace,
+		OnResult:    w.hotFolderResult,
+		OnError:     func(err error) { w.errorf("%v", err) },
+	})
+	h.Run(ctx)
+	return nil
+}
+
+func (w *watcher) hotFolderResult(job *srfax.HotFolderJob) {
+	ids := make([]string, len(job.IDs))
+	for i, id := range job.IDs {
+		ids[i] = strconv.Itoa(id)
+	}
+	line := fmt.Sprintf("%s to %s", job.File, strings.Join(job.To, ","))
+	switch {
+	case job.Error != "":
+		line += ": " + job.Error
+	case job.Sent():
+		line += ": Sent " + strings.Join(ids, ",")
+	default:
+		line += ": Failed " + strings.Join(ids, ",")
+	}
+	w.emit(watchEvent{Type: "hotfolder", Time: time.Now(), Job: job}, line+" -> "+job.Moved)
+}
@@ -0,0 +1,249 @@
+// Command srfax is a command-line client for the SRFax API.
+//
+// Usage:
+//
+//	srfax [-config file] [-json] <command> [flags] [args]
+//
+// Credentials are read from the SRFAX_ACCESS_ID and SRFAX_ACCESS_PWD environment variables,
+// and the default sender of send and forward from SRFAX_CALLER_ID and SRFAX_SENDER_EMAIL,
+// or all of them from a JSON config file:
+//
+//	{
+//	  "access_id": 12345,
+//	  "access_pwd": "password",
+//	  "caller_id": 6135550000,
+//	  "sender_email": "fax@example.com"
+//	}
+//
+// The config file defaults to $SRFAX_CONFIG, or srfax/config.json in the user config
+// directory. Environment variables take precedence over the config file.
+//
+// Run srfax help for the list of commands, and srfax <command> -h for their flags.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/mfridman/srfax"
+	"github.com/pkg/errors"
+)
+
+// command is a srfax subcommand.
+type command struct {
+	usage   string // arguments following the command name
+	summary string
+	run     func(ctx context.Context, env *env, args []string) error
+}
+
+var commands = map[string]*command{
+	"send":        cmdSend,
+	"inbox":       cmdInbox,
+	"outbox":      cmdOutbox,
+	"get":         cmdGet,
+	"status":      cmdStatus,
+	"forward":     cmdForward,
+	"stop":        cmdStop,
+	"delete":      cmdDelete,
+	"mark-read":   cmdMarkRead,
+	"mark-unread": cmdMarkUnread,
+	"usage":       cmdUsage,
+}
+
+// errUsage is returned by commands invoked with invalid arguments, after printing usage.
+var errUsage = errors.New("invalid usage")
+
+// env is the environment shared by all commands.
+type env struct {
+	cfg       *config
+	client    *srfax.Client
+	clientErr error
+	json      bool
+	stdout    io.Writer
+	stderr    io.Writer
+}
+
+// ready reports whether the client could be created from the credentials. It is checked
+// after parsing flags so -h works without credentials.
+func (e *env) ready() error {
+	if e.clientErr != nil {
+		return errors.Errorf("%v; set SRFAX_ACCESS_ID and SRFAX_ACCESS_PWD or use a config file", e.clientErr)
+	}
+	return nil
+}
+
+// config holds the credentials and sender defaults.
+type config struct {
+	AccessID    int    `json:"access_id"`
+	AccessPwd   string `json:"access_pwd"`
+	CallerID    int    `json:"caller_id"`
+	SenderEmail string `json:"sender_email"`
+}
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		cancel()
+	}()
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	cancel()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("srfax", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "", "path to the JSON config file")
+	asJSON := fs.Bool("json", false, "print JSON instead of tables")
+	fs.Usage = func() { printUsage(stderr) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || fs.Arg(0) == "help" {
+		printUsage(stderr)
+		return 2
+	}
+	name := fs.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "srfax: unknown command %q\n", name)
+		printUsage(stderr)
+		return 2
+	}
+
+	cfg, err := loadConfig(*configPath, os.Getenv)
+	if err != nil {
+		fmt.Fprintf(stderr, "srfax: %v\n", err)
+		return 1
+	}
+	e := &env{cfg: cfg, json: *asJSON, stdout: stdout, stderr: stderr}
+	e.client, e.clientErr = srfax.NewClient(srfax.ClientCfg{ID: cfg.AccessID, Pwd: cfg.AccessPwd})
+	if err := cmd.run(ctx, e, fs.Args()[1:]); err != nil {
+		if err == errUsage || err == flag.ErrHelp {
+			return 2
+		}
+		fmt.Fprintf(stderr, "srfax %s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: srfax [-config file] [-json] <command> [flags] [args]")
+	fmt.Fprintln(w, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, commands[name].summary)
+	}
+	tw.Flush()
+}
+
+// loadConfig reads the config file, if any, and applies environment overrides.
+// An explicitly given path must exist.
+func loadConfig(path string, getenv func(string) string) (*config, error) {
+	explicit := path != ""
+	if !explicit {
+		path = getenv("SRFAX_CONFIG")
+		explicit = path != ""
+	}
+	if !explicit {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "srfax", "config.json")
+		}
+	}
+
+	cfg := &config{}
+	if path != "" {
+		b, err := ioutil.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(b, cfg); err != nil {
+				return nil, errors.Wrapf(err, "failed to decode config %s", path)
+			}
+		case !os.IsNotExist(err) || explicit:
+			return nil, errors.Wrap(err, "failed to read config")
+		}
+	}
+
+	if v := getenv("SRFAX_ACCESS_ID"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.New("SRFAX_ACCESS_ID must be a number")
+		}
+		cfg.AccessID = id
+	}
+	if v := getenv("SRFAX_ACCESS_PWD"); v != "" {
+		cfg.AccessPwd = v
+	}
+	if v := getenv("SRFAX_CALLER_ID"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.New("SRFAX_CALLER_ID must be a number")
+		}
+		cfg.CallerID = id
+	}
+	if v := getenv("SRFAX_SENDER_EMAIL"); v != "" {
+		cfg.SenderEmail = v
+	}
+	return cfg, nil
+}
+
+// newFlagSet returns a flag set for a command that prints its usage on -h.
+func (e *env) newFlagSet(name string, cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "usage: srfax %s %s\n\n%s\n\n", name, cmd.usage, cmd.summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// print writes v as JSON, or rows as a table under the given header.
+func (e *env) print(v interface{}, header []string, rows [][]string) error {
+	if e.json {
+		enc := json.NewEncoder(e.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	tw := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// listFlag is a string flag that may be repeated or given as a comma separated list.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfridman/srfax"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+	b := []byte(`{"access_id": 123, "access_pwd": "file", "caller_id": 6135550000, "sender_email": "fax@example.com"}`)
+	if err := ioutil.WriteFile(path, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{"SRFAX_ACCESS_PWD": "env"}
+	cfg, err := loadConfig(path, func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AccessID != 123 || cfg.AccessPwd != "env" || cfg.CallerID != 6135550000 || cfg.SenderEmail != "fax@example.com" {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	env = map[string]string{"SRFAX_CONFIG": filepath.Join(dir, "missing.json")}
+	if _, err := loadConfig("", func(k string) string { return env[k] }); err == nil {
+		t.Fatal("want error for a missing explicit config file")
+	}
+	env = map[string]string{"SRFAX_ACCESS_ID": "abc"}
+	if _, err := loadConfig(path, func(k string) string { return env[k] }); err == nil {
+		t.Fatal("want error for a non numeric SRFAX_ACCESS_ID")
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	f, err := ioutil.TempFile("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("{}")
+	f.Close()
+	creds, err := ioutil.TempFile("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(creds.Name())
+	creds.WriteString(`{"access_id": 123, "access_pwd": "secret"}`)
+	creds.Close()
+
+	var tests = []struct {
+		args []string
+		code int
+		want string
+	}{
+		{nil, 2, "commands:"},
+		{[]string{"bogus"}, 2, `unknown command "bogus"`},
+		{[]string{"send", "-h"}, 2, "usage: srfax send"},
+		{[]string{"-config", f.Name(), "status", "1"}, 1, "SRFAX_ACCESS_ID"},
+		{[]string{"-config", creds.Name(), "watch", "-interval", "0"}, 2, "usage: srfax watch"},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), test.args, &stdout, &stderr)
+		if code != test.code || !strings.Contains(stderr.String(), test.want) {
+			t.Fatalf("run(%q) = %d, %q; want %d and output containing %q", test.args, code, stderr.String(), test.code, test.want)
+		}
+	}
+}
+
+func TestListFlag(t *testing.T) {
+	var l listFlag
+	l.Set("16135550000, 16135550001")
+	l.Set("16135550002")
+	if l.String() != "16135550000,16135550001,16135550002" {
+		t.Fatalf("unexpected list %v", l)
+	}
+}
+
+func TestWatchOutput(t *testing.T) {
+	var buf bytes.Buffer
+	w := &watcher{e: &env{stdout: &buf}}
+	w.received(srfax.FaxRecord{ID: 12124720, CallerID: "6135550000", Pages: 2}, "faxes/12124720.pdf")
+	w.status(srfax.StatusEvent{ID: 31524120, Previous: "In Progress", Result: srfax.MulFaxStatusResult{SentStatus: "Failed", ToFaxNumber: "16135550001", ErrorCode: "No Answer"}})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 lines; got %q", buf.String())
+	}
+	if want := "IN   12124720 from 6135550000, 2 pages, saved to faxes/12124720.pdf"; !strings.HasSuffix(lines[0], want) {
+		t.Errorf("want line ending in %q; got %q", want, lines[0])
+	}
+	if want := "OUT  31524120 to 16135550001: In Progress -> Failed (No Answer)"; !strings.HasSuffix(lines[1], want) {
+		t.Errorf("want line ending in %q; got %q", want, lines[1])
+	}
+
+	buf.Reset()
+	w.e.json = true
+	w.status(srfax.StatusEvent{ID: 31524120, Result: srfax.MulFaxStatusResult{SentStatus: "Sent"}})
+	var ev watchEvent
+	if err := json.Unmarshal(buf.Bytes(), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Type != "status" || ev.ID != 31524120 || ev.Status.SentStatus != "Sent" {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/mfridman/srfax"
+)
+
+var cmdPurge = &command{
+	usage:   "[-dry-run] <policy.json>",
+	summary: "delete faxes past their retention period, archiving them first if configured",
+}
+
+func init() {
+	cmdPurge.run = runPurge
+	commands["purge"] = cmdPurge
+}
+
+func runPurge(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("purge", cmdPurge)
+	dryRun := fs.Bool("dry-run", false, "report the faxes that would be deleted without deleting them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := e.ready(); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	policy, err := srfax.LoadRetentionPolicy(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	report, err := e.client.Purge(ctx, *policy, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	deleted := "deleted"
+	if *dryRun {
+		deleted = "would delete"
+	}
+	var rows [][]string
+	add := func(action string, items []srfax.RetentionItem, note func(srfax.RetentionItem) string) {
+		for _, item := range items {
+			r := item.Record
+			number := r.CallerID
+			if r.Direction == "OUT" {
+				number = r.ToFaxNumber
+			}
+			rows = append(rows, []string{action, r.Direction, strconv.Itoa(r.ID), r.Time.Format("2006-01-02"), number, r.Status, note(item)})
+		}
+	}
+	add(deleted, report.Deleted, func(item srfax.RetentionItem) string { return item.Path })
+	add("held", report.Held, func(item srfax.RetentionItem) string { return item.Hold })
+	add("failed", report.Failed, func(item srfax.RetentionItem) string { return item.Error })
+	if err := e.print(report, []string{"ACTION", "DIR", "ID", "DATE", "NUMBER", "STATUS", "NOTE"}, rows); err != nil {
+		return err
+	}
+	return report.Err()
+}
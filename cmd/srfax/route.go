@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mfridman/srfax"
+	"github.com/pkg/errors"
+)
+
+var cmdRoute = &command{
+	usage:   "[flags] <rules.json>",
+	summary: "apply routing rules to new inbound faxes until interrupted, or to past faxes with -dry-run",
+}
+
+func init() {
+	cmdRoute.run = runRoute
+	commands["route"] = cmdRoute
+}
+
+func runRoute(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("route", cmdRoute)
+	callerID := fs.Int("caller-id", e.cfg.CallerID, "sender fax number of forwarded faxes, 10 digits")
+	email := fs.String("email", e.cfg.SenderEmail, "sender email address of forwarded faxes")
+	dryRun := fs.Bool("dry-run", false, "print the actions the rules would apply to past faxes and exit")
+	since := fs.String("since", "", "with -dry-run, only faxes on or after this date, YYYY-MM-DD")
+	until := fs.String("until", "", "with -dry-run, only faxes before this date, YYYY-MM-DD")
+	interval := fs.Duration("interval", time.Minute, "delay between polls")
+	state := fs.String("state", "", "file remembering which faxes were routed, to continue after a restart")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := e.ready(); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	rules, err := srfax.LoadRules(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	router := e.client.NewRouter(rules, srfax.RouterOptions{CallerID: *callerID, SenderEmail: *email})
+
+	if *dryRun {
+		q := srfax.FaxQuery{}
+		if q.Since, err = parseDate("since", *since); err != nil {
+			return err
+		}
+		if q.Until, err = parseDate("until", *until); err != nil {
+			return err
+		}
+		if !q.Until.IsZero() && q.Since.IsZero() {
+			return errors.New("-until requires -since")
+		}
+		results, err := router.DryRun(ctx, q)
+		if err != nil {
+			return err
+		}
+		var rows [][]string
+		for _, res := range results {
+			r := res.Record
+			rows = append(rows, []string{strconv.Itoa(r.ID), r.Time.Format("2006-01-02 15:04"), r.CallerID, strings.Join(res.Rules, ", "), describeActions(res.Actions)})
+		}
+		return e.print(results, []string{"ID", "DATE", "FROM", "RULES", "ACTIONS"}, rows)
+	}
+
+	var store srfax.CursorStore = &srfax.MemoryCursorStore{}
+	if *state != "" {
+		store = &srfax.FileCursorStore{Path: *state}
+	}
+	w := &watcher{e: e, name: "route"}
+	fw := e.client.NewFaxWatcher(store, srfax.FaxWatcherOptions{Interval: *interval, OnError: func(err error) { w.errorf("%v", err) }})
+	fw.Run(ctx, func(r srfax.FaxRecord) error {
+		res, err := router.Route(ctx, r)
+		if err != nil {
+			// not retried, as routing again would repeat forwards and webhooks
+			w.errorf("%v", err)
+		}
+		line := fmt.Sprintf("IN   %d from %s: %s", r.ID, orUnknown(r.CallerID), describeActions(res.Actions))
+		w.emit(watchEvent{Type: "routed", Time: time.Now(), Record: &r, Route: res}, line)
+		return nil
+	})
+	return nil
+}
+
+// describeActions summarizes route actions, e.g., "save /srv/faxes/x.pdf, forward 16135550199".
+func describeActions(actions []srfax.RouteAction) string {
+	if len(actions) == 0 {
+		return "no matching rule"
+	}
+	var parts []string
+	for _, a := range actions {
+		s := a.Action.Type
+		switch a.Action.Type {
+		case srfax.ActionSave:
+			s += " " + a.Path
+		case srfax.ActionForward:
+			s += " " + strings.Join(a.Action.To, ",")
+		case srfax.ActionWebhook:
+			s += " " + a.Action.URL
+		}
+		if a.Error != "" {
+			s += " (" + a.Error + ")"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,8 @@
+//go:build go1.15
+// +build go1.15
+
+package main
+
+// Embed the timezone database, so the TimeZone of routing rules resolves on hosts without
+// one, e.g., minimal containers and Windows. It is only available from Go 1.15.
+import _ "time/tzdata"
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mfridman/srfax"
+	"github.com/pkg/errors"
+)
+
+var cmdWatch = &command{
+	usage:   "[flags]",
+	summary: "print new inbound faxes and outbound status changes until interrupted",
+}
+
+func init() {
+	cmdWatch.run = runWatch
+	commands["watch"] = cmdWatch
+}
+
+// watchEvent is a line printed by the long-running commands with -json.
+type watchEvent struct {
+	Type     string // received, status, hotfolder, routed, forwarded or blocked
+	Time     time.Time
+	Record   *srfax.FaxRecord          `json:",omitempty"`
+	Path     string                    `json:",omitempty"`
+	ID       int                       `json:",omitempty"`
+	Previous string                    `json:",omitempty"`
+	Status   *srfax.MulFaxStatusResult `json:",omitempty"`
+	Job      *srfax.HotFolderJob       `json:",omitempty"`
+	Route    *srfax.RouteResult        `json:",omitempty"`
+	Forward  *srfax.ForwardEntry       `json:",omitempty"`
+}
+
+func runWatch(ctx context.Context, e *env, args []string) error {
+	fs := e.newFlagSet("watch", cmdWatch)
+	interval := fs.Duration("interval", time.Minute, "delay between polls")
+	dir := fs.String("dir", "", "download new inbound faxes into this directory")
+	format := fs.String("format", "PDF", "PDF or TIFF, format of downloaded faxes")
+	state := fs.String("state", "", "file remembering which inbound faxes were printed, to continue after a restart")
+	lookback := fs.Duration("lookback", 24*time.Hour, "watch outbound faxes queued within this period")
+	unread := fs.Bool("unread", false, "watch unread inbound faxes instead of all new arrivals")
+	inbox := fs.Bool("inbox", true, "watch inbound faxes")
+	outbox := fs.Bool("outbox", true, "watch outbound faxes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := e.ready(); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || (!*inbox && !*outbox) || *interval <= 0 {
+		fs.Usage()
+		return errUsage
+	}
+	if *format != "PDF" && *format != "TIFF" {
+		return errors.New("-format must be PDF or TIFF")
+	}
+	if *dir != "" {
+		if err := os.MkdirAll(*dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	w := &watcher{e: e, name: "watch"}
+	onError := func(err error) { w.errorf("%v", err) }
+	var wg sync.WaitGroup
+
+	if *inbox {
+		var store srfax.CursorStore = &srfax.MemoryCursorStore{}
+		if *state != "" {
+			store = &srfax.FileCursorStore{Path: *state}
+		}
+		fw := e.client.NewFaxWatcher(store, srfax.FaxWatcherOptions{Interval: *interval, UnreadOnly: *unread, OnError: onError})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fw.Run(ctx, func(r srfax.FaxRecord) error {
+				path := ""
+				if *dir != "" {
+					ext := ".pdf"
+					if *format == "TIFF" {
+						ext = ".tif"
+					}
+					path = filepath.Join(*dir, strconv.Itoa(r.ID)+ext)
+					if _, err := e.client.RetrieveFaxToFile(ctx, r.Ref(), path, srfax.RetrieveOptions{FaxFormat: *format}); err != nil {
+						return err
+					}
+				}
+				w.received(r, path)
+				return nil
+			})
+		}()
+	}
+
+	if *outbox {
+		sw := e.client.NewStatusWatcher(srfax.StatusWatcherOptions{Interval: *interval, OnError: onError})
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			sw.Run(ctx)
+		}()
+		go func() {
+			defer wg.Done()
+			for ev := range sw.Events() {
+				w.status(ev)
+			}
+		}()
+		// Discover newly queued faxes, the status watcher drops them once they complete.
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(*interval)
+			defer ticker.Stop()
+			for {
+				q := srfax.FaxQuery{Direction: "OUT", Since: time.Now().Add(-*lookback)}
+				records, err := e.client.Faxes(ctx, q).All()
+				if err != nil && ctx.Err() == nil {
+					onError(err)
+				}
+				for _, r := range records {
+					if !srfax.IsTerminalSentStatus(r.Status) {
+						sw.Add(r.ID)
+					}
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// watcher prints the events of a long-running command one at a time.
+type watcher struct {
+	e    *env
+	name string
+	mu   sync.Mutex
+}
+
+func (w *watcher) emit(ev watchEvent, line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.e.json {
+		json.NewEncoder(w.e.stdout).Encode(ev)
+		return
+	}
+	fmt.Fprintf(w.e.stdout, "%s  %s\n", ev.Time.Format("2006-01-02 15:04:05"), line)
+}
+
+func (w *watcher) received(r srfax.FaxRecord, path string) {
+	line := fmt.Sprintf("IN   %d from %s, %d pages", r.ID, orUnknown(r.CallerID), r.Pages)
+	if path != "" {
+		line += ", saved to " + path
+	}
+	w.emit(watchEvent{Type: "received", Time: time.Now(), Record: &r, Path: path}, line)
+}
+
+func (w *watcher) status(ev srfax.StatusEvent) {
+	line := fmt.Sprintf("OUT  %d to %s: %s", ev.ID, orUnknown(ev.Result.ToFaxNumber), ev.Result.SentStatus)
+	if ev.Previous != "" {
+		line = fmt.Sprintf("OUT  %d to %s: %s -> %s", ev.ID, orUnknown(ev.Result.ToFaxNumber), ev.Previous, ev.Result.SentStatus)
+	}
+	if ev.Result.ErrorCode != "" {
+		line += " (" + ev.Result.ErrorCode + ")"
+	}
+	res := ev.Result
+	w.emit(watchEvent{Type: "status", Time: time.Now(), ID: ev.ID, Previous: ev.Previous, Status: &res}, line)
+}
+
+func (w *watcher) errorf(format string, args ...interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fmt.Fprintf(w.e.stderr, "srfax "+w.name+": "+format+"\n", args...)
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -38,3 +38,9 @@ const (
 	pdf = "PDF"
 	tif = "TIF"
 )
+
+const (
+	// "SentStatus" values that indicate an outbound fax is no longer in progress
+	sentStatusSent   = "Sent"
+	sentStatusFailed = "Failed"
+)
@@ -0,0 +1,360 @@
+package srfax
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultConfidentialityNotice is printed at the bottom of a CoverPage when Notice is blank.
+const DefaultConfidentialityNotice = "CONFIDENTIALITY NOTICE: The information contained in this facsimile is " +
+	"confidential and intended only for the use of the recipient named above. If you are not the " +
+	"intended recipient, you are hereby notified that any review, disclosure, copying, distribution " +
+	"or use of this information is strictly prohibited. If you have received this facsimile in error, " +
+	"please notify the sender immediately by telephone and destroy the original."
+
+// CoverPage is a branded fax cover page rendered locally to PDF, as an alternative to the
+// SRFax cover page templates selected with QueueOptions.CoverPage. Blank fields are omitted.
+//
+// Use WithCoverPage to prepend the rendered cover page to the files passed to QueueFax.
+type CoverPage struct {
+	// Branding printed in the page header. Logo must be a JPEG image
+	Company string
+	Logo    []byte
+
+	// Sender details
+	FromName  string
+	FromFax   string
+	FromPhone string
+	FromEmail string
+
+	// Recipient details
+	ToName         string
+	ToOrganization string
+	ToFax          string
+
+	Subject  string
+	Comments string
+
+	// Number of pages following the cover page. The cover page itself is added
+	// to the total printed on the page, which is omitted if Pages is zero
+	Pages int
+
+	// Printed at the bottom of the page, defaults to DefaultConfidentialityNotice
+	Notice string
+
+	// Date printed on the page, defaults to the current date
+	Date time.Time
+}
+
+// File renders the cover page and returns it as a base64-encoded File named cover.pdf.
+func (p *CoverPage) File() (File, error) {
+	b, err := p.PDF()
+	if err != nil {
+		return File{}, err
+	}
+	return File{Name: "cover.pdf", Content: base64.StdEncoding.EncodeToString(b)}, nil
+}
+
+// WithCoverPage renders p and returns a new slice with the cover page as the first file,
+// followed by files. The page count of files is not known, so p.Pages must be set to
+// print one.
+//
+// Do not also set QueueOptions.CoverPage, otherwise SRFax adds a second cover page.
+func WithCoverPage(p *CoverPage, files []File) ([]File, error) {
+	cover, err := p.File()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render cover page")
+	}
+	return append([]File{cover}, files...), nil
+}
+
+// US Letter page size and margins, in points.
+const (
+	pageWidth   = 612
+	pageHeight  = 792
+	pageMargin  = 54
+	columnWidth = pageWidth - 2*pageMargin
+)
+
+// PDF renders the cover page as a single page PDF document.
+func (p *CoverPage) PDF() ([]byte, error) {
+	var img *pdfImage
+	if len(p.Logo) > 0 {
+		var err error
+		if img, err = newPDFImage(p.Logo); err != nil {
+			return nil, err
+		}
+	}
+
+	var c pdfContent
+	y := float64(pageHeight - pageMargin)
+
+	// header: logo and company name
+	if img != nil {
+		h := 48.0
+		w := h * float64(img.width) / float64(img.height)
+		c.image(pageMargin, y-h, w, h)
+		if p.Company != "" {
+			c.text(fontBold, 20, pageMargin+w+12, y-32, p.Company)
+		}
+		y -= h + 24
+	} else if p.Company != "" {
+		c.text(fontBold, 20, pageMargin, y-20, p.Company)
+		y -= 44
+	}
+
+	c.text(fontBold, 40, pageMargin, y-40, "FAX")
+	y -= 56
+	c.rule(pageMargin, y, columnWidth, 1.5)
+	y -= 28
+
+	date := p.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	pages := ""
+	if p.Pages > 0 {
+		pages = fmt.Sprintf("%d (including cover page)", p.Pages+1)
+	}
+	rows := [][2]string{
+		{"Date:", date.Format("January 2, 2006")},
+		{"To:", p.ToName},
+		{"Organization:", p.ToOrganization},
+		{"Fax:", p.ToFax},
+		{"From:", p.FromName},
+		{"Fax:", p.FromFax},
+		{"Phone:", p.FromPhone},
+		{"Email:", p.FromEmail},
+		{"Subject:", p.Subject},
+		{"Pages:", pages},
+	}
+	for _, row := range rows {
+		if row[1] == "" {
+			continue
+		}
+		c.text(fontBold, 12, pageMargin, y, row[0])
+		for i, line := range wrapText(row[1], 12, columnWidth-110) {
+			if i > 0 {
+				y -= 16
+			}
+			c.text(fontRegular, 12, pageMargin+110, y, line)
+		}
+		y -= 22
+	}
+
+	y -= 4
+	c.rule(pageMargin, y, columnWidth, 0.75)
+	y -= 28
+
+	if p.Comments != "" {
+		c.text(fontBold, 12, pageMargin, y, "Comments:")
+		y -= 20
+		for _, line := range wrapText(p.Comments, 11, columnWidth) {
+			if y < 140 {
+				break
+			}
+			c.text(fontRegular, 11, pageMargin, y, line)
+			y -= 15
+		}
+	}
+
+	notice := p.Notice
+	if notice == "" {
+		notice = DefaultConfidentialityNotice
+	}
+	lines := wrapText(notice, 8, columnWidth)
+	ny := float64(pageMargin) + float64(len(lines)-1)*10
+	c.rule(pageMargin, ny+16, columnWidth, 0.5)
+	for _, line := range lines {
+		c.text(fontRegular, 8, pageMargin, ny, line)
+		ny -= 10
+	}
+
+	return writePDF(c.Bytes(), img), nil
+}
+
+type pdfFont string
+
+const (
+	fontRegular pdfFont = "/F1"
+	fontBold    pdfFont = "/F2"
+)
+
+// pdfContent is a PDF page content stream.
+type pdfContent struct {
+	bytes.Buffer
+}
+
+func (c *pdfContent) text(font pdfFont, size, x, y float64, s string) {
+	fmt.Fprintf(c, "BT %s %g Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfEscape(s))
+}
+
+func (c *pdfContent) rule(x, y, width, thickness float64) {
+	fmt.Fprintf(c, "%g w %.2f %.2f m %.2f %.2f l S\n", thickness, x, y, x+width, y)
+}
+
+func (c *pdfContent) image(x, y, w, h float64) {
+	fmt.Fprintf(c, "q %.2f 0 0 %.2f %.2f %.2f cm /Im1 Do Q\n", w, h, x, y)
+}
+
+// pdfImage is a JPEG image embedded as-is using the DCTDecode filter.
+type pdfImage struct {
+	data          []byte
+	width, height int
+	colorSpace    string
+}
+
+func newPDFImage(b []byte) (*pdfImage, error) {
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.Wrap(err, "Logo must be a JPEG image")
+	}
+	img := &pdfImage{data: b, width: cfg.Width, height: cfg.Height, colorSpace: "/DeviceRGB"}
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		img.colorSpace = "/DeviceGray"
+	case color.CMYKModel:
+		img.colorSpace = "/DeviceCMYK"
+	}
+	return img, nil
+}
+
+// writePDF assembles a single page PDF document around content.
+func writePDF(content []byte, img *pdfImage) []byte {
+	var buf bytes.Buffer
+	offsets := make([]int, 0, 7)
+	obj := func(body string, stream []byte) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\n", len(offsets), body)
+		if stream != nil {
+			buf.WriteString("stream\n")
+			buf.Write(stream)
+			buf.WriteString("\nendstream\n")
+		}
+		buf.WriteString("endobj\n")
+	}
+
+	xobject := ""
+	if img != nil {
+		xobject = " /XObject << /Im1 7 0 R >>"
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	obj("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	obj("<< /Type /Pages /Kids [3 0 R] /Count 1 >>", nil)
+	obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 4 0 R /F2 5 0 R >>%s >> /Contents 6 0 R >>",
+		pageWidth, pageHeight, xobject), nil)
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>", nil)
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>", nil)
+	obj(fmt.Sprintf("<< /Length %d >>", len(content)), content)
+	if img != nil {
+		obj(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>",
+			img.width, img.height, img.colorSpace, len(img.data)), img.data)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
+
+// winAnsi maps the non-Latin-1 characters of WinAnsiEncoding commonly found in text.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92,
+	'“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// pdfEscape encodes s in WinAnsiEncoding as the body of a PDF literal string.
+// Characters that cannot be encoded are replaced with a question mark.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteByte(' ')
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			if c, ok := winAnsi[r]; ok {
+				fmt.Fprintf(&b, "\\%03o", c)
+			} else {
+				b.WriteByte('?')
+			}
+		}
+	}
+	return b.String()
+}
+
+// helveticaWidths are the glyph widths of Helvetica for the printable ASCII characters,
+// in thousandths of the font size, starting at the space character.
+var helveticaWidths = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// textWidth returns the width of s in points when set in Helvetica at size.
+func textWidth(s string, size float64) float64 {
+	var w int
+	for _, r := range s {
+		if r >= ' ' && int(r-' ') < len(helveticaWidths) {
+			w += helveticaWidths[r-' ']
+		} else {
+			w += 556
+		}
+	}
+	return float64(w) * size / 1000
+}
+
+// wrapText breaks s into lines no wider than width when set in Helvetica at size.
+// Newlines in s are preserved and words longer than a line are split.
+func wrapText(s string, size, width float64) []string {
+	var lines []string
+	for _, para := range strings.Split(strings.Replace(s, "\r\n", "\n", -1), "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			for len([]rune(word)) > 1 && textWidth(word, size) > width {
+				// hard-split a word that cannot fit on a line of its own
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				n := len([]rune(word)) - 1
+				for n > 1 && textWidth(string([]rune(word)[:n]), size) > width {
+					n--
+				}
+				lines = append(lines, string([]rune(word)[:n]))
+				word = string([]rune(word)[n:])
+			}
+			switch {
+			case line == "":
+				line = word
+			case textWidth(line+" "+word, size) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
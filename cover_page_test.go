@@ -0,0 +1,106 @@
+package srfax
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCoverPagePDF(t *testing.T) {
+	var logo bytes.Buffer
+	if err := jpeg.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 40, 20)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &CoverPage{
+		Company:  "Acme (Canada) Inc.",
+		Logo:     logo.Bytes(),
+		FromName: "Wile E. Coyote",
+		ToName:   "Road Runner",
+		ToFax:    "1-613-555-1234",
+		Subject:  `Re: \ invoice – 2018`,
+		Comments: strings.Repeat("Lorem ipsum dolor sit amet. ", 40),
+		Pages:    3,
+		Date:     time.Date(2018, 3, 19, 0, 0, 0, 0, time.UTC),
+	}
+	b, err := p.PDF()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(b, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(b, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+	for _, want := range []string{
+		`(Acme \(Canada\) Inc.) Tj`,
+		`(Re: \\ invoice \226 2018) Tj`,
+		`(4 \(including cover page\)) Tj`,
+		`(March 19, 2018) Tj`,
+		"/Filter /DCTDecode",
+	} {
+		if !bytes.Contains(b, []byte(want)) {
+			t.Errorf("PDF missing %q", want)
+		}
+	}
+
+	// every xref entry must point at the start of its object
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(b)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	entries := strings.Split(string(b[xref:]), "\n")[3:]
+	for i := 1; i <= 7; i++ {
+		off, err := strconv.Atoi(strings.Fields(entries[i-1])[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("%d 0 obj", i); !bytes.HasPrefix(b[off:], []byte(want)) {
+			t.Fatalf("xref entry %d does not point at %q", i, want)
+		}
+	}
+
+	if _, err := (&CoverPage{Logo: []byte("not a jpeg")}).PDF(); err == nil {
+		t.Fatal("want error for invalid logo")
+	}
+}
+
+func TestWithCoverPage(t *testing.T) {
+	files := []File{{Name: "a.pdf", Content: "aGVsbG8="}, {Name: "b.pdf", Content: "aGVsbG8="}}
+	got, err := WithCoverPage(&CoverPage{ToName: "Road Runner"}, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Name != "cover.pdf" || got[1].Name != "a.pdf" {
+		t.Fatalf("want cover page prepended; got %+v", got)
+	}
+	b, err := base64.StdEncoding.DecodeString(got[0].Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("including cover page")) {
+		t.Fatal("want no page count unless Pages is set")
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	lines := wrapText("one two three\n"+strings.Repeat("x", 300), 12, 100)
+	for _, l := range lines {
+		if textWidth(l, 12) > 100 {
+			t.Fatalf("line %q wider than 100pt", l)
+		}
+	}
+	if lines[0] != "one two three" && lines[0] != "one two" {
+		t.Fatalf("unexpected first line %q", lines[0])
+	}
+	if got := strings.Join(lines[len(lines)-5:], ""); !strings.HasSuffix(got, "xxx") {
+		t.Fatalf("long word lost: %q", got)
+	}
+}
@@ -1,6 +1,7 @@
 package srfax
 
 import (
+	"context"
 	"strconv"
 	"strings"
 
@@ -28,6 +29,10 @@ type mappedDeleteResp struct {
 // Note, this method will take care of formatting ids accordingly, so it is
 // safe to mix filenames with IDs: []string{"20170721124555-1213-4_0|272568938", "172568938"}
 func (c *Client) DeleteFax(ids []string, direction string) (*DeleteResp, error) {
+	return c.deleteFax(context.Background(), ids, direction)
+}
+
+func (c *Client) deleteFax(ctx context.Context, ids []string, direction string) (*DeleteResp, error) {
 	if !(direction == inbound || direction == outbound) {
 		return nil, errors.Errorf("direction must be one of either %q or %q", inbound, outbound)
 	}
@@ -58,7 +63,7 @@ func (c *Client) DeleteFax(ids []string, direction string) (*DeleteResp, error)
 	}
 
 	result := mappedDeleteResp{}
-	if err := run(operation, &result, c.url); err != nil {
+	if err := runContext(ctx, operation, &result, c.url); err != nil {
 		return nil, err
 	}
 
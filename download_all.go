@@ -0,0 +1,196 @@
+package srfax
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DownloadOptions specify optional arguments for DownloadAll.
+type DownloadOptions struct {
+	// Number of faxes retrieved at once, defaults to 4
+	Concurrency int
+
+	// PDF or TIFF, defaults to PDF
+	FaxFormat string
+
+	// Check every download with CheckIntegrity and against the page count of its record,
+	// downloading it again on mismatch
+	Verify bool
+
+	// Optional callback invoked after every fax, from multiple goroutines one at a time
+	Progress func(DownloadProgress)
+}
+
+// DownloadProgress reports the outcome of a single fax in DownloadAll.
+type DownloadProgress struct {
+	Record FaxRecord
+
+	// Path of the fax file
+	Path string
+
+	// True if the file was already present and not downloaded again
+	Skipped bool
+
+	// Error retrieving or writing the fax, if any
+	Err error
+
+	// Number of faxes processed so far, including this one, out of Total
+	Done, Total int
+}
+
+// DownloadReport summarizes a DownloadAll run. Paths and errors are keyed by FaxFileName.
+type DownloadReport struct {
+	Downloaded map[string]string
+	Skipped    map[string]string
+	Failed     map[string]error
+}
+
+// Err returns an error summarizing the failed faxes, or nil if every fax was downloaded or skipped.
+func (r *DownloadReport) Err() error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(r.Failed))
+	for name := range r.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return errors.Errorf("failed to download %d faxes, first %s: %v", len(names), names[0], r.Failed[names[0]])
+}
+
+// DownloadAll retrieves every fax matching q into dir, along with a JSON sidecar holding its
+// FaxRecord. Files are named after the direction, date, FaxDetailsID and remote number of the
+// fax, e.g., IN_20170101-230101_12124720_6135550000.pdf, so running DownloadAll again skips
+// faxes that are already present.
+//
+// Faxes are retrieved concurrently. An error is returned only if the faxes could not be listed,
+// failures of individual faxes are reported in the DownloadReport.
+func (c *Client) DownloadAll(ctx context.Context, dir string, q FaxQuery, options ...DownloadOptions) (*DownloadReport, error) {
+	opts := DownloadOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.FaxFormat == "" {
+		opts.FaxFormat = "PDF"
+	}
+	if !(opts.FaxFormat == "PDF" || opts.FaxFormat == "TIFF") {
+		return nil, errors.New("FaxFormat must be one of: PDF or TIFF")
+	}
+
+	records, err := c.Faxes(ctx, q).All()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create download directory")
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		work   = make(chan FaxRecord)
+		report = &DownloadReport{
+			Downloaded: make(map[string]string),
+			Skipped:    make(map[string]string),
+			Failed:     make(map[string]error),
+		}
+	)
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range work {
+				p := DownloadProgress{Record: r, Path: filepath.Join(dir, downloadName(r, opts.FaxFormat))}
+				p.Skipped, p.Err = c.downloadRecord(ctx, r, p.Path, &opts)
+
+				mu.Lock()
+				switch {
+				case p.Err != nil:
+					report.Failed[r.FileName] = p.Err
+				case p.Skipped:
+					report.Skipped[r.FileName] = p.Path
+				default:
+					report.Downloaded[r.FileName] = p.Path
+				}
+				p.Done = len(report.Failed) + len(report.Skipped) + len(report.Downloaded)
+				p.Total = len(records)
+				if opts.Progress != nil {
+					opts.Progress(p)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, r := range records {
+		work <- r
+	}
+	close(work)
+	wg.Wait()
+	return report, nil
+}
+
+// downloadRecord retrieves a single fax to path and writes its sidecar, unless path exists.
+func (c *Client) downloadRecord(ctx context.Context, r FaxRecord, path string, opts *DownloadOptions) (skipped bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	sidecar := path + ".json"
+	if _, err := os.Stat(path); err == nil {
+		skipped = true
+	} else {
+		ropts := RetrieveOptions{FaxFormat: opts.FaxFormat}
+		if opts.Verify {
+			// Size refers to the account's default format, only the page count can be checked
+			_, err = c.RetrieveFaxVerified(ctx, r.Ref(), path, VerifyOptions{ExpectPages: r.Pages}, ropts)
+		} else {
+			_, err = c.RetrieveFaxToFile(ctx, r.Ref(), path, ropts)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	if _, err := os.Stat(sidecar); err == nil {
+		return skipped, nil
+	}
+	if err := writeJSONFile(sidecar, r); err != nil {
+		return skipped, errors.Wrap(err, "failed to write sidecar")
+	}
+	return skipped, nil
+}
+
+// downloadName returns the file name of a downloaded fax: direction, date and time of the
+// FaxFileName, FaxDetailsID and the sender or recipient number.
+func downloadName(r FaxRecord, format string) string {
+	stamp := r.Time.UTC().Format("20060102150405")
+	if i := strings.IndexByte(r.FileName, '-'); i == 14 {
+		stamp = r.FileName[:14]
+	}
+	remote := r.CallerID
+	if r.Direction == outbound {
+		remote = r.ToFaxNumber
+	}
+	remote = strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, remote)
+	if remote == "" {
+		remote = "unknown"
+	}
+	ext := ".pdf"
+	if format == "TIFF" {
+		ext = ".tif"
+	}
+	return fmt.Sprintf("%s_%s-%s_%d_%s%s", r.Direction, stamp[:8], stamp[8:], r.ID, remote, ext)
+}
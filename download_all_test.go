@@ -0,0 +1,95 @@
+package srfax
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDownloadAll(t *testing.T) {
+
+	var retrieves int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		var resp map[string]interface{}
+		switch req["action"] {
+		case actionGetFaxInbox:
+			resp = map[string]interface{}{"Status": "Success", "Result": []map[string]interface{}{
+				{"EpochTime": 1514847661, "FileName": "20180101230101-8812-34_0|100", "CallerID": "(613) 555-0000", "Pages": 1},
+				{"EpochTime": 1514934061, "FileName": "20180102230101-8812-34_0|101", "CallerID": "", "Pages": 2},
+				{"EpochTime": 1515020461, "FileName": "20180103230101-8812-34_0|102", "CallerID": "4165550000", "Pages": 1},
+			}}
+		case actionRetrieveFax:
+			atomic.AddInt32(&retrieves, 1)
+			if req["sFaxFileName"] == "20180103230101-8812-34_0|102" {
+				resp = map[string]interface{}{"Status": "Failed", "Result": "Fax not found"}
+				break
+			}
+			resp = map[string]interface{}{"Status": "Success", "Result": base64.StdEncoding.EncodeToString([]byte("%PDF-1.4"))}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	client := &Client{account: account{9090, "abc"}, url: srv.URL}
+	q := FaxQuery{Direction: "IN", Since: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), Until: time.Date(2018, 1, 5, 0, 0, 0, 0, time.UTC)}
+
+	var progress int
+	report, err := client.DownloadAll(context.Background(), dir, q, DownloadOptions{
+		Concurrency: 2,
+		Progress: func(p DownloadProgress) {
+			progress++
+			if p.Total != 3 || p.Done != progress {
+				t.Errorf("unexpected progress %d/%d", p.Done, p.Total)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Downloaded) != 2 || len(report.Failed) != 1 || report.Err() == nil || progress != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	path := filepath.Join(dir, "IN_20180101-230101_100_6135550000.pdf")
+	if report.Downloaded["20180101230101-8812-34_0|100"] != path {
+		t.Fatalf("unexpected paths %v", report.Downloaded)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "IN_20180102-230101_101_unknown.pdf")); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sidecar FaxRecord
+	if err := json.Unmarshal(b, &sidecar); err != nil || sidecar.ID != 100 || sidecar.Pages != 1 {
+		t.Fatalf("unexpected sidecar %s: %v", b, err)
+	}
+
+	// a second run only retries the failed fax
+	report, err = client.DownloadAll(context.Background(), dir, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Skipped) != 2 || len(report.Failed) != 1 || retrieves != 4 {
+		t.Fatalf("want 2 skipped and 1 retried; got %+v after %d retrieves", report, retrieves)
+	}
+}
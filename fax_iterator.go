@@ -0,0 +1,344 @@
+package srfax
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FaxRecord is a received or sent fax, normalized from an InboxResult or OutboxResult so
+// inbound and outbound faxes can be handled alike.
+type FaxRecord struct {
+	// IN or OUT for inbound or outbound
+	Direction string
+
+	// FaxFileName and the FaxDetailsID parsed from it
+	FileName string
+	ID       int
+
+	// EpochTime of the fax
+	Time time.Time
+
+	// ReceiveStatus of an inbound fax or SentStatus of an outbound fax
+	Status string
+
+	// Sender number of an inbound fax
+	CallerID string
+	// Recipient number of an outbound fax
+	ToFaxNumber string
+
+	RemoteID      string
+	UserID        string
+	UserFaxNumber string
+	Pages         int
+	Size          int
+
+	// Inbound only
+	ViewedStatus string
+
+	// Outbound only
+	Subject     string
+	AccountCode string
+	ErrorCode   string
+}
+
+// Ref returns a reference to the fax, e.g., to retrieve it with RetrieveFaxTo.
+func (r *FaxRecord) Ref() FaxRef { return FaxRef{Ident: r.FileName, Direction: r.Direction} }
+
+func inboxRecord(r InboxResult) FaxRecord {
+	id, _ := IDFromName(r.FileName)
+	return FaxRecord{
+		Direction:     inbound,
+		FileName:      r.FileName,
+		ID:            id,
+		Time:          time.Unix(int64(r.EpochTime), 0),
+		Status:        r.ReceiveStatus,
+		CallerID:      r.CallerID,
+		RemoteID:      r.RemoteID,
+		UserID:        r.UserID,
+		UserFaxNumber: r.UserFaxNumber,
+		Pages:         r.Pages,
+		Size:          r.Size,
+		ViewedStatus:  r.ViewedStatus,
+	}
+}
+
+func outboxRecord(r OutboxResult) FaxRecord {
+	id, _ := IDFromName(r.FileName)
+	epoch, _ := strconv.ParseInt(r.EpochTime, 10, 64)
+	return FaxRecord{
+		Direction:     outbound,
+		FileName:      r.FileName,
+		ID:            id,
+		Time:          time.Unix(epoch, 0),
+		Status:        r.SentStatus,
+		ToFaxNumber:   r.ToFaxNumber,
+		RemoteID:      r.RemoteID,
+		UserID:        r.UserID,
+		UserFaxNumber: r.UserFaxNumber,
+		Pages:         r.Pages,
+		Size:          r.Size,
+		Subject:       r.Subject,
+		AccountCode:   r.AccountCode,
+		ErrorCode:     r.ErrorCode,
+	}
+}
+
+// FaxQuery selects inbound or outbound faxes for Client.Faxes. Filters left at their zero
+// value match every fax.
+type FaxQuery struct {
+	// IN or OUT for inbound or outbound, required
+	Direction string
+
+	// Only faxes with an EpochTime within [Since, Until) are returned. When Since is set the
+	// period is fetched with RANGE queries of at most Window each, otherwise all faxes
+	// are fetched at once. Until defaults to the current time
+	Since, Until time.Time
+
+	// Length of each RANGE query, rounded to whole days, defaults to 7 days
+	Window time.Duration
+
+	// Inbound only: READ, UNREAD or ALL
+	ViewedStatus string
+
+	// Include faxes of sub users of the account
+	IncludeSubUsers bool
+
+	// Fax numbers are compared ignoring formatting, RemoteID ignoring case
+	CallerID      string
+	ToFaxNumber   string
+	UserFaxNumber string
+	RemoteID      string
+
+	// Inclusive page count and size (bytes) bounds, zero means unbounded
+	MinPages, MaxPages int
+	MinSize, MaxSize   int
+
+	// Faxes are returned oldest first, or newest first if Descending is set
+	Descending bool
+}
+
+func (q *FaxQuery) validate() error {
+	if !(q.Direction == inbound || q.Direction == outbound) {
+		return errors.Errorf("Direction must be one of: %s or %s", inbound, outbound)
+	}
+	if q.ViewedStatus != "" && q.Direction != inbound {
+		return errors.New("ViewedStatus can only be used with inbound faxes")
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Since.Before(q.Until) {
+		return errors.New("Since must be before Until")
+	}
+	if q.Window < 0 {
+		return errors.New("Window cannot be negative")
+	}
+	return nil
+}
+
+// match reports whether r satisfies the query's client-side filters.
+func (q *FaxQuery) match(r *FaxRecord) bool {
+	switch {
+	case !q.Since.IsZero() && r.Time.Before(q.Since),
+		!q.Until.IsZero() && !r.Time.Before(q.Until),
+		q.CallerID != "" && !sameNumber(q.CallerID, r.CallerID),
+		q.ToFaxNumber != "" && !sameNumber(q.ToFaxNumber, r.ToFaxNumber),
+		q.UserFaxNumber != "" && !sameNumber(q.UserFaxNumber, r.UserFaxNumber),
+		q.RemoteID != "" && !strings.EqualFold(strings.TrimSpace(q.RemoteID), strings.TrimSpace(r.RemoteID)),
+		q.MinPages > 0 && r.Pages < q.MinPages,
+		q.MaxPages > 0 && r.Pages > q.MaxPages,
+		q.MinSize > 0 && r.Size < q.MinSize,
+		q.MaxSize > 0 && r.Size > q.MaxSize:
+		return false
+	}
+	return true
+}
+
+// sameNumber compares two fax numbers ignoring formatting and the NANP country code.
+func sameNumber(a, b string) bool {
+	digits := func(s string) string {
+		var sb strings.Builder
+		for _, r := range s {
+			if r >= '0' && r <= '9' {
+				sb.WriteRune(r)
+			}
+		}
+		d := sb.String()
+		if len(d) == 11 && d[0] == '1' {
+			d = d[1:]
+		}
+		return d
+	}
+	da := digits(a)
+	return da != "" && da == digits(b)
+}
+
+// dateWindow is an inclusive range of dates in YYYYMMDD format, as used by RANGE queries.
+type dateWindow struct {
+	start, end string
+}
+
+// dateWindows splits the dates from since to until, inclusive, into consecutive windows
+// of at most days days each. Dates are taken in the location of since.
+func dateWindows(since, until time.Time, days int) []dateWindow {
+	if days < 1 {
+		days = 1
+	}
+	const layout = "20060102"
+	y, m, d := since.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, since.Location())
+	last := until.In(since.Location()).Format(layout)
+
+	var out []dateWindow
+	for start.Format(layout) <= last {
+		end := start.AddDate(0, 0, days-1)
+		if end.Format(layout) > last {
+			end, _ = time.ParseInLocation(layout, last, since.Location())
+		}
+		out = append(out, dateWindow{start.Format(layout), end.Format(layout)})
+		start = end.AddDate(0, 0, 1)
+	}
+	return out
+}
+
+// FaxIterator iterates over the faxes selected by a FaxQuery, fetching one date window at
+// a time. Use it as follows:
+//
+//	it := client.Faxes(ctx, srfax.FaxQuery{Direction: "IN", Since: since})
+//	for it.Next() {
+//		r := it.Record()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type FaxIterator struct {
+	c   *Client
+	ctx context.Context
+	q   FaxQuery
+
+	windows []dateWindow
+	fetched bool
+	buf     []FaxRecord
+	cur     FaxRecord
+	seen    map[string]bool
+	err     error
+}
+
+// Faxes returns an iterator over the inbound or outbound faxes selected by q.
+func (c *Client) Faxes(ctx context.Context, q FaxQuery) *FaxIterator {
+	it := &FaxIterator{c: c, ctx: ctx, q: q, seen: make(map[string]bool)}
+	if err := q.validate(); err != nil {
+		it.err = err
+		return it
+	}
+	if !q.Since.IsZero() {
+		if it.q.Until.IsZero() {
+			it.q.Until = time.Now()
+		}
+		window := q.Window
+		if window == 0 {
+			window = 7 * 24 * time.Hour
+		}
+		it.windows = dateWindows(q.Since, it.q.Until, int(window/(24*time.Hour)))
+		if q.Descending {
+			for i, j := 0, len(it.windows)-1; i < j; i, j = i+1, j-1 {
+				it.windows[i], it.windows[j] = it.windows[j], it.windows[i]
+			}
+		}
+	}
+	return it
+}
+
+// Next advances the iterator to the next fax, fetching the next date window if required.
+// It returns false when there are no more faxes or an error occurred.
+func (it *FaxIterator) Next() bool {
+	for len(it.buf) == 0 {
+		if it.err != nil {
+			return false
+		}
+		if it.fetched && len(it.windows) == 0 {
+			return false
+		}
+		var w *dateWindow
+		if len(it.windows) > 0 {
+			w = &it.windows[0]
+			it.windows = it.windows[1:]
+		}
+		it.fetched = true
+		it.buf, it.err = it.fetch(w)
+	}
+	it.cur, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+// Record returns the current fax. It is only valid after Next returned true.
+func (it *FaxIterator) Record() FaxRecord { return it.cur }
+
+// Err returns the error, if any, that stopped the iteration.
+func (it *FaxIterator) Err() error { return it.err }
+
+// All drains the iterator and returns the remaining faxes.
+func (it *FaxIterator) All() ([]FaxRecord, error) {
+	var out []FaxRecord
+	for it.Next() {
+		out = append(out, it.Record())
+	}
+	return out, it.Err()
+}
+
+// fetch retrieves one date window, or all faxes if w is nil, and returns the matching
+// records sorted by time.
+func (it *FaxIterator) fetch(w *dateWindow) ([]FaxRecord, error) {
+	if err := it.ctx.Err(); err != nil {
+		return nil, err
+	}
+	period, start, end := "ALL", "", ""
+	if w != nil {
+		period, start, end = "RANGE", w.start, w.end
+	}
+	subUsers := ""
+	if it.q.IncludeSubUsers {
+		subUsers = yes
+	}
+
+	var records []FaxRecord
+	switch it.q.Direction {
+	case inbound:
+		opts := &InboxOptions{Period: period, StartDate: start, EndDate: end, ViewedStatus: it.q.ViewedStatus, IncludeSubUsers: subUsers}
+		inbox, err := it.c.getFaxInbox(it.ctx, opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list inbox")
+		}
+		for _, r := range inbox.Result {
+			records = append(records, inboxRecord(r))
+		}
+	case outbound:
+		opts := &OutboxOptions{Period: period, StartDate: start, EndDate: end, IncludeSubUsers: subUsers}
+		outbox, err := it.c.getFaxOutbox(it.ctx, opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list outbox")
+		}
+		for _, r := range outbox.Result {
+			records = append(records, outboxRecord(r))
+		}
+	}
+
+	out := records[:0]
+	for i := range records {
+		if it.seen[records[i].FileName] || !it.q.match(&records[i]) {
+			continue
+		}
+		it.seen[records[i].FileName] = true
+		out = append(out, records[i])
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if it.q.Descending {
+			return out[i].Time.After(out[j].Time)
+		}
+		return out[i].Time.Before(out[j].Time)
+	})
+	return out, nil
+}
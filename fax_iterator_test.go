@@ -0,0 +1,112 @@
+package srfax
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDateWindows(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2018, 1, d, 13, 0, 0, 0, time.UTC) }
+	var tests = []struct {
+		since, until time.Time
+		days         int
+		want         []dateWindow
+	}{
+		{day(1), day(1), 7, []dateWindow{{"20180101", "20180101"}}},
+		{day(1), day(10), 7, []dateWindow{{"20180101", "20180107"}, {"20180108", "20180110"}}},
+		{day(1), day(14), 7, []dateWindow{{"20180101", "20180107"}, {"20180108", "20180114"}}},
+		{day(1), day(3), 0, []dateWindow{{"20180101", "20180101"}, {"20180102", "20180102"}, {"20180103", "20180103"}}},
+		{day(5), day(1), 7, nil},
+	}
+	for _, test := range tests {
+		got := dateWindows(test.since, test.until, test.days)
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Fatalf("dateWindows(%v, %v, %d) = %v; want %v", test.since, test.until, test.days, got, test.want)
+		}
+	}
+}
+
+func TestFaxIterator(t *testing.T) {
+
+	// three inbound faxes per day on Jan 1-10 2018, one every 8 hours
+	base := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, fmt.Sprintf("%v-%v", req["sStartDate"], req["sEndDate"]))
+		start, _ := time.Parse("20060102", req["sStartDate"].(string))
+		end, _ := time.Parse("20060102", req["sEndDate"].(string))
+
+		result := make([]map[string]interface{}, 0)
+		for i := 0; i < 30; i++ {
+			ts := base.Add(time.Duration(i) * 8 * time.Hour)
+			if ts.Before(start) || !ts.Before(end.AddDate(0, 0, 1)) {
+				continue
+			}
+			caller := "6135550000"
+			if i%3 == 0 {
+				caller = "(416) 555-0000"
+			}
+			result = append(result, map[string]interface{}{
+				"FileName":  fmt.Sprintf("%s-1234-1_0|%d", ts.Format("20060102150405"), 1000+i),
+				"EpochTime": ts.Unix(),
+				"CallerID":  caller,
+				"Pages":     i % 5,
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Status": "Success", "Result": result})
+	}))
+	defer srv.Close()
+
+	client := &Client{account: account{9090, "abc"}, url: srv.URL}
+
+	q := FaxQuery{
+		Direction: "IN",
+		Since:     base,
+		Until:     base.AddDate(0, 0, 10),
+		Window:    4 * 24 * time.Hour,
+		CallerID:  "1-416-555-0000",
+		MinPages:  1,
+	}
+	got, err := client.Faxes(context.Background(), q).All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("want 3 RANGE requests; got %v", requests)
+	}
+	if len(got) != 8 {
+		t.Fatalf("want 8 matching faxes; got %d", len(got))
+	}
+	for i, r := range got {
+		if r.CallerID != "(416) 555-0000" || r.Pages < 1 || r.Direction != "IN" {
+			t.Fatalf("record does not match filters: %+v", r)
+		}
+		if i > 0 && r.Time.Before(got[i-1].Time) {
+			t.Fatal("records not in ascending order")
+		}
+	}
+
+	q.Descending = true
+	got, err = client.Faxes(context.Background(), q).All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Time.After(got[i-1].Time) {
+			t.Fatal("records not in descending order")
+		}
+	}
+
+	if _, err := client.Faxes(context.Background(), FaxQuery{Direction: "SIDEWAYS"}).All(); err == nil {
+		t.Fatal("want error for invalid direction")
+	}
+}
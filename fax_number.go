@@ -0,0 +1,140 @@
+package srfax
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// internationalPrefix is the NANP international dialing prefix SRFax expects in front of
+// the country code of a number outside North America.
+const internationalPrefix = "011"
+
+// FaxNumber is a fax number normalized to the wire format SRFax expects in sToFaxNumber:
+// 11 digits for North American (NANP) numbers, e.g., 16135551234, and the 011 prefix
+// followed by the country code and subscriber number for international numbers,
+// e.g., 011442079460000.
+//
+// Use ParseFaxNumber to construct a FaxNumber from user input.
+type FaxNumber string
+
+// ParseFaxNumber parses and validates a fax number as typed by a user. Spaces, dashes,
+// dots and parentheses are ignored. The following forms are accepted:
+//
+//	613 555 1234, (613) 555-1234, 1-613-555-1234, +1 613 555 1234
+//	+44 20 7946 0000, 011 44 20 7946 0000
+//
+// NANP numbers must have a valid area code and exchange, i.e., neither may begin
+// with 0 or 1 or be of the form N11, and the area code may not be of the reserved form N9X.
+func ParseFaxNumber(s string) (FaxNumber, error) {
+	var b strings.Builder
+	plus := false
+	for i, r := range strings.TrimSpace(s) {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		case r == '+' && i == 0:
+			plus = true
+		default:
+			return "", errors.Errorf("fax number %q contains invalid character %q", s, r)
+		}
+	}
+	digits := b.String()
+
+	switch {
+	case plus && strings.HasPrefix(digits, "1"):
+		return parseNANP(s, digits[1:])
+	case plus:
+		return parseInternational(s, digits)
+	case strings.HasPrefix(digits, internationalPrefix):
+		return parseInternational(s, digits[len(internationalPrefix):])
+	case len(digits) == 11 && strings.HasPrefix(digits, "1"):
+		return parseNANP(s, digits[1:])
+	case len(digits) == 10:
+		return parseNANP(s, digits)
+	}
+	return "", errors.Errorf("fax number %q must be a 10 digit NANP number, optionally prefixed with 1, or an international number prefixed with + or %s", s, internationalPrefix)
+}
+
+// parseNANP validates a 10 digit NANP number, i.e., without the leading 1.
+func parseNANP(s, digits string) (FaxNumber, error) {
+	if len(digits) != 10 {
+		return "", errors.Errorf("fax number %q must have 10 digits following the country code 1", s)
+	}
+	area, exchange := digits[:3], digits[3:6]
+	if !validNXX(area) || area[1] == '9' {
+		return "", errors.Errorf("fax number %q has an invalid area code: %s", s, area)
+	}
+	if !validNXX(exchange) {
+		return "", errors.Errorf("fax number %q has an invalid exchange: %s", s, exchange)
+	}
+	return FaxNumber("1" + digits), nil
+}
+
+// parseInternational validates an international number, i.e., country code followed by the
+// subscriber number, limited to the 15 digits allowed by E.164.
+func parseInternational(s, digits string) (FaxNumber, error) {
+	if strings.HasPrefix(digits, "0") || strings.HasPrefix(digits, "1") {
+		return "", errors.Errorf("fax number %q has an invalid country code", s)
+	}
+	if len(digits) < 7 || len(digits) > 15 {
+		return "", errors.Errorf("international fax number %q must have between 7 and 15 digits including the country code", s)
+	}
+	return FaxNumber(internationalPrefix + digits), nil
+}
+
+// validNXX reports whether a NANP area code or exchange begins with 2-9 and is not of the form N11.
+func validNXX(s string) bool {
+	return s[0] >= '2' && s[0] <= '9' && s[1:] != "11"
+}
+
+// String returns the number in sToFaxNumber wire format.
+func (n FaxNumber) String() string { return string(n) }
+
+// IsNANP reports whether n is a North American number.
+func (n FaxNumber) IsNANP() bool { return len(n) == 11 && strings.HasPrefix(string(n), "1") }
+
+// AreaCode returns the 3 digit area code of a NANP number, or a blank string for international numbers.
+func (n FaxNumber) AreaCode() string {
+	if !n.IsNANP() {
+		return ""
+	}
+	return string(n[1:4])
+}
+
+// CallerID returns the number in sCallerID wire format, i.e., 10 digits without the leading 1.
+// Only NANP numbers can be used as a CallerID.
+func (n FaxNumber) CallerID() (int, error) {
+	if !n.IsNANP() {
+		return 0, errors.Errorf("CallerID must be a NANP number: %s", n)
+	}
+	return strconv.Atoi(string(n[1:]))
+}
+
+// ParseFaxNumbers parses each of ss with ParseFaxNumber and returns the numbers in wire format,
+// ready for use in QueueCfg.ToFaxNumber or ForwardCfg.ToFaxNumber. All invalid numbers are
+// reported in the returned error.
+func ParseFaxNumbers(ss ...string) ([]string, error) {
+	out := make([]string, 0, len(ss))
+	invalid := make([]string, 0)
+	for _, s := range ss {
+		n, err := ParseFaxNumber(s)
+		if err != nil {
+			invalid = append(invalid, s)
+			continue
+		}
+		out = append(out, n.String())
+	}
+	if len(invalid) > 0 {
+		return nil, errors.Errorf("invalid fax number(s): %s", strings.Join(invalid, ", "))
+	}
+	return out, nil
+}
+
+// isWireFaxNumber reports whether s is a valid fax number already in sToFaxNumber wire format.
+func isWireFaxNumber(s string) bool {
+	n, err := ParseFaxNumber(s)
+	return err == nil && n.String() == s
+}
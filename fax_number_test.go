@@ -0,0 +1,79 @@
+package srfax
+
+import (
+	"testing"
+)
+
+func TestParseFaxNumber(t *testing.T) {
+	var tests = []struct {
+		in   string
+		want FaxNumber
+		ok   bool
+	}{
+		{"6135551234", "16135551234", true},
+		{"613 555 1234", "16135551234", true},
+		{"(613) 555-1234", "16135551234", true},
+		{"1-613-555-1234", "16135551234", true},
+		{"+1 613.555.1234", "16135551234", true},
+		{" 16135551234 ", "16135551234", true},
+		{"+44 20 7946 0000", "011442079460000", true},
+		{"011 44 20 7946 0000", "011442079460000", true},
+		{"011442079460000", "011442079460000", true},
+		{"", "", false},
+		{"555-1234", "", false},
+		{"1235551234", "", false},     // area code begins with 1
+		{"9115551234", "", false},     // N11 area code
+		{"6935551234", "", false},     // reserved N9X area code
+		{"6131551234", "", false},     // exchange begins with 1
+		{"6134111234", "", false},     // N11 exchange
+		{"+1 613 555 123", "", false}, // too short
+		{"613-555-1234 x12", "", false},
+		{"+0 123 4567", "", false},
+		{"+44 20", "", false},
+		{"+44 2079 4600 0012 345", "", false}, // longer than E.164
+		{"61+3555 1234", "", false},
+	}
+	for _, test := range tests {
+		got, err := ParseFaxNumber(test.in)
+		if (err == nil) != test.ok {
+			t.Fatalf("ParseFaxNumber(%q) error = %v; want ok=%t", test.in, err, test.ok)
+		}
+		if got != test.want {
+			t.Fatalf("ParseFaxNumber(%q) = %q; want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestFaxNumberCallerID(t *testing.T) {
+	n, err := ParseFaxNumber("+1 (613) 555-1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := n.CallerID(); err != nil || got != 6135551234 {
+		t.Fatalf("CallerID() = %d, %v; want 6135551234", got, err)
+	}
+	if got := n.AreaCode(); got != "613" {
+		t.Fatalf("AreaCode() = %q; want 613", got)
+	}
+
+	intl := FaxNumber("011442079460000")
+	if _, err := intl.CallerID(); err == nil {
+		t.Fatal("want error for international CallerID")
+	}
+	if intl.AreaCode() != "" {
+		t.Fatal("want blank area code for international number")
+	}
+}
+
+func TestParseFaxNumbers(t *testing.T) {
+	got, err := ParseFaxNumbers("613-555-1234", "+44 20 7946 0000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "16135551234" || got[1] != "011442079460000" {
+		t.Fatalf("ParseFaxNumbers = %v", got)
+	}
+	if _, err := ParseFaxNumbers("613-555-1234", "oops", "911"); err == nil {
+		t.Fatal("want error for invalid numbers")
+	}
+}
@@ -0,0 +1,234 @@
+package srfax
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FaxCursor records which inbound faxes a FaxWatcher has already emitted: every fax received
+// before Since, and the faxes in Seen, mapped to the time they were received.
+type FaxCursor struct {
+	Since time.Time
+	Seen  map[string]time.Time
+}
+
+// CursorStore persists a FaxCursor between runs so a FaxWatcher does not emit the same
+// fax again after a restart.
+type CursorStore interface {
+	// Load returns the saved cursor, or nil if none has been saved yet.
+	Load() (*FaxCursor, error)
+
+	// Save persists the cursor.
+	Save(*FaxCursor) error
+}
+
+// FileCursorStore is a CursorStore that keeps the cursor in a JSON file at Path.
+type FileCursorStore struct {
+	Path string
+}
+
+// Load implements CursorStore.
+func (s *FileCursorStore) Load() (*FaxCursor, error) {
+	b, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read cursor")
+	}
+	var c FaxCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode cursor %s", s.Path)
+	}
+	return &c, nil
+}
+
+// Save implements CursorStore.
+func (s *FileCursorStore) Save(c *FaxCursor) error {
+	return writeJSONFile(s.Path, c)
+}
+
+// MemoryCursorStore is a CursorStore that keeps the cursor in memory, e.g., for tests or
+// short-lived processes.
+type MemoryCursorStore struct {
+	mu sync.Mutex
+	c  *FaxCursor
+}
+
+// Load implements CursorStore.
+func (s *MemoryCursorStore) Load() (*FaxCursor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.c == nil {
+		return nil, nil
+	}
+	return s.c.copy(), nil
+}
+
+// Save implements CursorStore.
+func (s *MemoryCursorStore) Save(c *FaxCursor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c = c.copy()
+	return nil
+}
+
+func (c *FaxCursor) copy() *FaxCursor {
+	out := &FaxCursor{Since: c.Since, Seen: make(map[string]time.Time, len(c.Seen))}
+	for k, v := range c.Seen {
+		out.Seen[k] = v
+	}
+	return out
+}
+
+// FaxWatcherOptions specify optional arguments for a FaxWatcher.
+type FaxWatcherOptions struct {
+	// Delay between polls, defaults to 1 minute
+	Interval time.Duration
+
+	// Poll UNREAD faxes instead of a RANGE query since the cursor
+	UnreadOnly bool
+
+	// Faxes received before Start are not emitted when no cursor has been saved yet.
+	// Defaults to the time of the first poll
+	Start time.Time
+
+	// How long after they were received faxes may still show up in the inbox, i.e., the
+	// cursor trails the current time by Overlap. Defaults to 24 hours
+	Overlap time.Duration
+
+	// Include faxes received by sub users of the account
+	IncludeSubUsers bool
+
+	// Optional callback invoked with errors encountered while polling or returned by the
+	// handler. The watcher keeps polling after an error
+	OnError func(error)
+}
+
+// FaxWatcher polls the inbox and emits every newly received fax once. Emitted faxes are
+// recorded in a CursorStore after each successful emit, so a restarted watcher continues
+// where it left off.
+//
+// A fax is emitted again only if the process stops after the handler returned but before
+// the cursor was saved, or if the handler returned an error.
+type FaxWatcher struct {
+	c     *Client
+	store CursorStore
+	opts  FaxWatcherOptions
+
+	cursor *FaxCursor
+}
+
+// NewFaxWatcher returns a FaxWatcher that persists its progress in store.
+func (c *Client) NewFaxWatcher(store CursorStore, opts FaxWatcherOptions) *FaxWatcher {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.Overlap <= 0 {
+		opts.Overlap = 24 * time.Hour
+	}
+	return &FaxWatcher{c: c, store: store, opts: opts}
+}
+
+// Run polls the inbox every Interval until ctx is cancelled, calling fn once for every new
+// fax, oldest first. If fn returns an error the fax is retried on the next poll.
+// Run returns ctx.Err() once ctx is cancelled.
+func (w *FaxWatcher) Run(ctx context.Context, fn func(FaxRecord) error) error {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := w.Poll(ctx, fn); err != nil && ctx.Err() == nil && w.opts.OnError != nil {
+			w.opts.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Start runs the watcher in a new goroutine and returns a channel on which new faxes are
+// delivered. The channel is closed once ctx is cancelled. A fax is recorded as emitted once
+// it has been received from the channel.
+func (w *FaxWatcher) Start(ctx context.Context) <-chan FaxRecord {
+	ch := make(chan FaxRecord)
+	go func() {
+		defer close(ch)
+		w.Run(ctx, func(r FaxRecord) error {
+			select {
+			case ch <- r:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return ch
+}
+
+// Poll checks the inbox once and calls fn for every new fax, oldest first. It stops at the
+// first error returned by fn.
+func (w *FaxWatcher) Poll(ctx context.Context, fn func(FaxRecord) error) error {
+	now := time.Now()
+	if w.cursor == nil {
+		c, err := w.store.Load()
+		if err != nil {
+			return err
+		}
+		if c == nil {
+			c = &FaxCursor{Since: w.opts.Start}
+			if c.Since.IsZero() {
+				c.Since = now
+			}
+		}
+		if c.Seen == nil {
+			c.Seen = make(map[string]time.Time)
+		}
+		w.cursor = c
+	}
+
+	q := FaxQuery{Direction: inbound, IncludeSubUsers: w.opts.IncludeSubUsers}
+	if w.opts.UnreadOnly {
+		q.ViewedStatus = "UNREAD"
+	} else {
+		q.Since = w.cursor.Since
+		q.Until = now.Add(24 * time.Hour)
+		q.Window = 7 * 24 * time.Hour
+	}
+	it := w.c.Faxes(ctx, q)
+	for it.Next() {
+		r := it.Record()
+		if _, ok := w.cursor.Seen[r.FileName]; ok || r.Time.Before(w.cursor.Since) {
+			continue
+		}
+		if err := fn(r); err != nil {
+			return errors.Wrapf(err, "failed to handle fax %s", r.FileName)
+		}
+		w.cursor.Seen[r.FileName] = r.Time
+		if err := w.store.Save(w.cursor); err != nil {
+			return errors.Wrap(err, "failed to save cursor")
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	// Every fax before the overlap has now been emitted, forget about them.
+	if since := now.Add(-w.opts.Overlap); since.After(w.cursor.Since) {
+		w.cursor.Since = since
+		for name, t := range w.cursor.Seen {
+			if t.Before(since) {
+				delete(w.cursor.Seen, name)
+			}
+		}
+		return w.store.Save(w.cursor)
+	}
+	return nil
+}
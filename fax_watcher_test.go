@@ -0,0 +1,89 @@
+package srfax
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFaxWatcher(t *testing.T) {
+
+	var (
+		mu    sync.Mutex
+		inbox []map[string]interface{}
+	)
+	receive := func(ts time.Time, id int) {
+		mu.Lock()
+		defer mu.Unlock()
+		inbox = append(inbox, map[string]interface{}{
+			"FileName":  fmt.Sprintf("%s-1234-1_0|%d", ts.Format("20060102150405"), id),
+			"EpochTime": ts.Unix(),
+		})
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"Status": "Success", "Result": inbox})
+	}))
+	defer srv.Close()
+
+	client := &Client{account: account{9090, "abc"}, url: srv.URL}
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := &FileCursorStore{Path: filepath.Join(dir, "cursor.json")}
+
+	now := time.Now()
+	receive(now.Add(-3*time.Hour), 1) // before Start, never emitted
+	receive(now.Add(-time.Hour), 2)
+	receive(now.Add(-time.Minute), 3)
+
+	var got []int
+	collect := func(r FaxRecord) error {
+		got = append(got, r.ID)
+		return nil
+	}
+	opts := FaxWatcherOptions{Start: now.Add(-2 * time.Hour)}
+	ctx := context.Background()
+
+	w := client.NewFaxWatcher(store, opts)
+	if err := w.Poll(ctx, collect); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[2 3]" {
+		t.Fatalf("first poll emitted %v; want [2 3]", got)
+	}
+
+	// a failing handler is retried on the next poll
+	receive(now, 4)
+	if err := w.Poll(ctx, func(FaxRecord) error { return errors.New("oops") }); err == nil {
+		t.Fatal("want handler error returned")
+	}
+	if err := w.Poll(ctx, collect); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[2 3 4]" {
+		t.Fatalf("second poll emitted %v; want [2 3 4]", got)
+	}
+
+	// a restarted watcher continues from the stored cursor
+	w = client.NewFaxWatcher(store, opts)
+	receive(now.Add(time.Second), 5)
+	if err := w.Poll(ctx, collect); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[2 3 4 5]" {
+		t.Fatalf("restarted watcher emitted %v; want [2 3 4 5]", got)
+	}
+}
@@ -1,6 +1,7 @@
 package srfax
 
 import (
+	"context"
 	"strconv"
 	"strings"
 
@@ -59,6 +60,9 @@ func (o *ForwardOptions) validate() error {
 	if o.QueueFaxTime != "" && o.QueueFaxDate == "" {
 		return errors.New("QueueFaxDate cannot be blank when supplying QueueFaxTime")
 	}
+	if o.QueueFaxDate == "" {
+		return nil
+	}
 	if ok := validDateOrTime("2006-01-02", o.QueueFaxDate); !ok {
 		return errors.New("QueueFaxDate must have format: YYYY-MM-DD")
 	}
@@ -89,7 +93,7 @@ type ForwardCfg struct {
 
 	// SINGLE when sending to one number; BROADCAST when sending to multiple numbers
 	FaxType string `json:"sFaxType"`
-	// Slice of string representing an 11 digit fax number
+	// Slice of string representing an 11 digit fax number, see ParseFaxNumbers
 	ToFaxNumber []string `json:"-"`
 }
 
@@ -109,12 +113,12 @@ func (c *ForwardCfg) validate() error {
 	}
 	invalid := make([]string, 0)
 	for i, n := range c.ToFaxNumber {
-		if ok := isNChars(n, 11); !ok {
+		if ok := isWireFaxNumber(n); !ok {
 			invalid = append(invalid, c.ToFaxNumber[i])
 		}
 	}
 	if len(invalid) > 0 {
-		return errors.Errorf("to fax number(s) must be 11 digits, found errors: %s", strings.Join(invalid, ", "))
+		return errors.Errorf("to fax number(s) must be 11 digit NANP or %s-prefixed international numbers, found errors: %s", internationalPrefix, strings.Join(invalid, ", "))
 	}
 	if len(c.ToFaxNumber) > 1 {
 		if c.FaxType != broadcast {
@@ -124,8 +128,9 @@ func (c *ForwardCfg) validate() error {
 	if len(c.ToFaxNumber) == 1 && c.FaxType != single {
 		return errors.Errorf("when supplying one fax number in ToFaxNumber, the FaxType must be %s", single)
 	}
-	if ok := isNChars(strconv.Itoa(c.CallerID), 10); !ok {
-		return errors.Errorf("CallerID must be 10 digits: %d", c.CallerID)
+	callerID := strconv.Itoa(c.CallerID)
+	if n, err := ParseFaxNumber(callerID); err != nil || !isNChars(callerID, 10) || !n.IsNANP() {
+		return errors.Errorf("CallerID must be a valid 10 digit NANP number: %d", c.CallerID)
 	}
 	return nil
 }
@@ -157,10 +162,20 @@ func newForwardOperation(c *Client, cfg *ForwardCfg, opts *ForwardOptions) *forw
 }
 
 // ForwardFax forwards a fax to other fax numbers.
+//
+// If any of the recipients are on the client's SuppressionList nothing is forwarded and
+// a *SuppressedError is returned.
 func (c *Client) ForwardFax(cfg ForwardCfg, options ...ForwardOptions) (*ForwardResp, error) {
+	return c.forwardFax(context.Background(), cfg, options...)
+}
+
+func (c *Client) forwardFax(ctx context.Context, cfg ForwardCfg, options ...ForwardOptions) (*ForwardResp, error) {
 	if err := cfg.validate(); err != nil {
 		return nil, err
 	}
+	if err := c.checkSuppressed(cfg.ToFaxNumber); err != nil {
+		return nil, err
+	}
 	opts := ForwardOptions{}
 	if len(options) > 0 {
 		if err := options[0].validate(); err != nil {
@@ -175,7 +190,7 @@ func (c *Client) ForwardFax(cfg ForwardCfg, options ...ForwardOptions) (*Forward
 	}
 
 	result := mappedForwardResp{}
-	if err := run(operation, &result, c.url); err != nil {
+	if err := runContext(ctx, operation, &result, c.url); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,48 @@
+package srfax
+
+import "testing"
+
+func TestForwardCfgValidate(t *testing.T) {
+	tt := []struct {
+		callerID int
+		valid    bool
+	}{
+		{6135551234, true},
+		{16135551234, false}, // sCallerID must be exactly 10 digits
+		{613555123, false},
+		{1135551234, false}, // invalid area code
+	}
+	for _, tc := range tt {
+		c := &ForwardCfg{
+			FaxDetailsID: "30294755",
+			Direction:    "IN",
+			CallerID:     tc.callerID,
+			SenderEmail:  "email@example.com",
+			FaxType:      "SINGLE",
+			ToFaxNumber:  []string{"16135550199"},
+		}
+		if err := c.validate(); (err == nil) != tc.valid {
+			t.Errorf("CallerID %d: got err %v; want valid %t", tc.callerID, err, tc.valid)
+		}
+	}
+}
+
+func TestForwardOptionsValidate(t *testing.T) {
+	tt := []struct {
+		opts  ForwardOptions
+		valid bool
+	}{
+		{ForwardOptions{}, true},
+		{ForwardOptions{AccountCode: "abc", Retries: 2}, true},
+		{ForwardOptions{QueueFaxDate: "2018-01-02", QueueFaxTime: "13:30"}, true},
+		{ForwardOptions{QueueFaxDate: "2018-01-02"}, false},
+		{ForwardOptions{QueueFaxTime: "13:30"}, false},
+		{ForwardOptions{QueueFaxDate: "01/02/2018", QueueFaxTime: "13:30"}, false},
+		{ForwardOptions{QueueFaxDate: "2018-01-02", QueueFaxTime: "1:30pm"}, false},
+	}
+	for _, tc := range tt {
+		if err := tc.opts.validate(); (err == nil) != tc.valid {
+			t.Errorf("%+v: got err %v; want valid %t", tc.opts, err, tc.valid)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package srfax
 
-import "github.com/pkg/errors"
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
 
 // InboxOptions specify optional arguments when retrieving inbox items.
 type InboxOptions struct {
@@ -18,6 +22,9 @@ type InboxOptions struct {
 
 	// Set to Y to include faxes received by a sub user of the account as well
 	IncludeSubUsers string `json:"sIncludeSubUsers,omitempty"`
+
+	// Optionally split a RANGE query into smaller date windows, see RangeSplit
+	Split RangeSplit `json:"-"`
 }
 
 func (o *InboxOptions) validate() error {
@@ -38,6 +45,9 @@ func (o *InboxOptions) validate() error {
 	if o.IncludeSubUsers != "" && o.IncludeSubUsers != yes {
 		return errors.Errorf("IncludeSubUsers must be omitted or set to %q", yes)
 	}
+	if err := o.Split.validate(o.Period); err != nil {
+		return err
+	}
 	if o.ViewedStatus != "" {
 		switch o.ViewedStatus {
 		case "UNREAD", "READ", "ALL":
@@ -69,19 +79,22 @@ type mappedInbox struct {
 // Inbox represents fax inbox information.
 type Inbox struct {
 	Status string
-	Result []struct {
-		FileName      string
-		ReceiveStatus string
-		Date          string
-		CallerID      string
-		RemoteID      string
-		ViewedStatus  string
-		UserID        string
-		UserFaxNumber string
-		EpochTime     int
-		Pages         int
-		Size          int
-	}
+	Result []InboxResult
+}
+
+// InboxResult is a single received fax within an Inbox.
+type InboxResult = struct {
+	FileName      string
+	ReceiveStatus string
+	Date          string
+	CallerID      string
+	RemoteID      string
+	ViewedStatus  string
+	UserID        string
+	UserFaxNumber string
+	EpochTime     int
+	Pages         int
+	Size          int
 }
 
 // Total returns number of unique inbox items in Result.
@@ -130,14 +143,20 @@ func (c *Client) GetFaxInbox(options ...InboxOptions) (*Inbox, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed options")
 	}
+	if opts.Split.Days > 0 {
+		return c.getFaxInboxSplit(context.Background(), opts)
+	}
+	return c.getFaxInbox(context.Background(), opts)
+}
 
+func (c *Client) getFaxInbox(ctx context.Context, opts *InboxOptions) (*Inbox, error) {
 	operation, err := constructReader(newInboxOperation(c, opts))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to construct a reader from newInboxOperation struct")
 	}
 
 	result := mappedInbox{}
-	if err := run(operation, &result, c.url); err != nil {
+	if err := runContext(ctx, operation, &result, c.url); err != nil {
 		return nil, err
 	}
 
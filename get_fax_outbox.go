@@ -1,6 +1,10 @@
 package srfax
 
-import "github.com/pkg/errors"
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
 
 // OutboxOptions specify optional arguments when retrieving outbox items.
 type OutboxOptions struct {
@@ -13,6 +17,9 @@ type OutboxOptions struct {
 
 	// Set to Y to include faxes received by a sub user of the account as well
 	IncludeSubUsers string `json:"sIncludeSubUsers,omitempty"`
+
+	// Optionally split a RANGE query into smaller date windows, see RangeSplit
+	Split RangeSplit `json:"-"`
 }
 
 func (o *OutboxOptions) validate() error {
@@ -33,6 +40,9 @@ func (o *OutboxOptions) validate() error {
 	if o.IncludeSubUsers != "" && o.IncludeSubUsers != yes {
 		return errors.Errorf("IncludeSubUsers must be omitted or set to %q", yes)
 	}
+	if err := o.Split.validate(o.Period); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -40,23 +50,26 @@ func (o *OutboxOptions) validate() error {
 // https://www.srfax.com/api-page/get_fax_outbox/, look for JSON Returned Variables.
 type Outbox struct {
 	Status string
-	Result []struct {
-		FileName      string
-		SentStatus    string
-		DateQueued    string
-		DateSent      string
-		EpochTime     string
-		ToFaxNumber   string
-		RemoteID      string
-		ErrorCode     string
-		AccountCode   string
-		Subject       string
-		UserID        string
-		UserFaxNumber string
-		Pages         int
-		Duration      int
-		Size          int
-	}
+	Result []OutboxResult
+}
+
+// OutboxResult is a single sent fax within an Outbox.
+type OutboxResult = struct {
+	FileName      string
+	SentStatus    string
+	DateQueued    string
+	DateSent      string
+	EpochTime     string
+	ToFaxNumber   string
+	RemoteID      string
+	ErrorCode     string
+	AccountCode   string
+	Subject       string
+	UserID        string
+	UserFaxNumber string
+	Pages         int
+	Duration      int
+	Size          int
 }
 
 // Total returns number of unique outbox items in Result.
@@ -126,14 +139,20 @@ func (c *Client) GetFaxOutbox(options ...OutboxOptions) (*Outbox, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed options")
 	}
+	if opts.Split.Days > 0 {
+		return c.getFaxOutboxSplit(context.Background(), opts)
+	}
+	return c.getFaxOutbox(context.Background(), opts)
+}
 
+func (c *Client) getFaxOutbox(ctx context.Context, opts *OutboxOptions) (*Outbox, error) {
 	operation, err := constructReader(newOutboxOperation(c, opts))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to construct a reader from newOutboxOperation")
 	}
 
 	result := mappedOutbox{}
-	if err := run(operation, &result, c.url); err != nil {
+	if err := runContext(ctx, operation, &result, c.url); err != nil {
 		return nil, err
 	}
 
@@ -18,7 +18,7 @@ func TestNewOutboxOperation(t *testing.T) {
 			o    *OutboxOptions
 			want map[string]interface{}
 		}{
-			&Client{account{925, "abc"}, ""}, &OutboxOptions{}, map[string]interface{}{
+			&Client{account: account{925, "abc"}}, &OutboxOptions{}, map[string]interface{}{
 				"action": "Get_Fax_Outbox", "access_id": 925, "access_pwd": "abc"},
 		}
 
@@ -58,7 +58,7 @@ func TestNewOutboxOperation(t *testing.T) {
 			o    *OutboxOptions
 			want map[string]interface{}
 		}{
-			&Client{account{925, "abc"}, ""}, &OutboxOptions{Period: "ALL"}, map[string]interface{}{
+			&Client{account: account{925, "abc"}}, &OutboxOptions{Period: "ALL"}, map[string]interface{}{
 				"action": "Get_Fax_Outbox", "access_id": 925, "access_pwd": "abc", "sPeriod": "ALL"},
 		}
 
@@ -209,7 +209,7 @@ func TestGetFaxOutbox(t *testing.T) {
 	t.Run("valid response, no options", func(t *testing.T) {
 
 		// no need to call NewClient, because we want to pass a mock URL
-		client := Client{account{9090, "abc"}, srv.URL}
+		client := Client{account: account{9090, "abc"}, url: srv.URL}
 
 		outbox, err := client.GetFaxOutbox()
 		if err != nil {
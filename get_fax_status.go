@@ -1,6 +1,8 @@
 package srfax
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 )
 
@@ -55,6 +57,10 @@ func newFaxStatusOperation(c *Client, id int) *faxStatusOperation {
 // GetFaxStatus retrieves the status of a single sent fax. Works only with outbound faxes.
 // Accepts a single id, i.e., FaxDetailsID, which is the result value from QueueFax or ForwardFax.
 func (c *Client) GetFaxStatus(id int) (*FaxStatus, error) {
+	return c.getFaxStatus(context.Background(), id)
+}
+
+func (c *Client) getFaxStatus(ctx context.Context, id int) (*FaxStatus, error) {
 	if id <= 0 {
 		return nil, errors.New("id cannot be zero or negative number")
 	}
@@ -65,7 +71,7 @@ func (c *Client) GetFaxStatus(id int) (*FaxStatus, error) {
 	}
 
 	result := mappedFaxStatus{}
-	if err := run(operation, &result, c.url); err != nil {
+	if err := runContext(ctx, operation, &result, c.url); err != nil {
 		return nil, err
 	}
 
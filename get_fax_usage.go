@@ -1,6 +1,8 @@
 package srfax
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 )
 
@@ -15,6 +17,9 @@ type FaxUsageOptions struct {
 
 	// Set to Y to include faxes received by a sub user of the account as well
 	IncludeSubUsers string `json:"sIncludeSubUsers,omitempty"`
+
+	// Optionally split a RANGE query into smaller date windows, see RangeSplit
+	Split RangeSplit `json:"-"`
 }
 
 func (o *FaxUsageOptions) validate() error {
@@ -35,6 +40,9 @@ func (o *FaxUsageOptions) validate() error {
 	if o.IncludeSubUsers != "" && o.IncludeSubUsers != yes {
 		return errors.Errorf("IncludeSubUsers must be blank or set to %q", yes)
 	}
+	if err := o.Split.validate(o.Period); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -85,14 +93,20 @@ func (c *Client) GetFaxUsage(options ...FaxUsageOptions) (*FaxUsage, error) {
 		}
 		opts = options[0]
 	}
+	if opts.Split.Days > 0 {
+		return c.getFaxUsageSplit(context.Background(), &opts)
+	}
+	return c.getFaxUsage(context.Background(), &opts)
+}
 
-	operation, err := constructReader(newFaxUsageOperation(c, &opts))
+func (c *Client) getFaxUsage(ctx context.Context, opts *FaxUsageOptions) (*FaxUsage, error) {
+	operation, err := constructReader(newFaxUsageOperation(c, opts))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to construct a reader from newFaxUsageOperation")
 	}
 
 	result := mappedFaxUsage{}
-	if err := run(operation, &result, c.url); err != nil {
+	if err := runContext(ctx, operation, &result, c.url); err != nil {
 		return nil, err
 	}
 
@@ -1,6 +1,7 @@
 package srfax
 
 import (
+	"context"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -9,20 +10,23 @@ import (
 // MulFaxStatus represents the status of multiple sent faxes.
 type MulFaxStatus struct {
 	Status string
-	Result []struct {
-		Pages       string
-		EpochTime   string
-		Duration    string
-		Size        string
-		FileName    string
-		SentStatus  string
-		DateQueued  string
-		DateSent    string
-		ToFaxNumber string
-		RemoteID    string
-		ErrorCode   string
-		AccountCode string
-	}
+	Result []MulFaxStatusResult
+}
+
+// MulFaxStatusResult is the status of a single fax within a MulFaxStatus.
+type MulFaxStatusResult = struct {
+	Pages       string
+	EpochTime   string
+	Duration    string
+	Size        string
+	FileName    string
+	SentStatus  string
+	DateQueued  string
+	DateSent    string
+	ToFaxNumber string
+	RemoteID    string
+	ErrorCode   string
+	AccountCode string
 }
 
 type mappedMulFaxStatus struct {
@@ -58,6 +62,10 @@ func newMulFaxUsageOperation(c *Client, ids []string) *mulFaxStatusOperation {
 // Accepts a multiple id, i.e., FaxDetailsID, which is the result value from QueueFax or ForwardFax.
 // Note, this method will take care of formatting ids accordingly with pipe(s).
 func (c *Client) GetMulFaxStatus(ids []string) (*MulFaxStatus, error) {
+	return c.getMulFaxStatus(context.Background(), ids)
+}
+
+func (c *Client) getMulFaxStatus(ctx context.Context, ids []string) (*MulFaxStatus, error) {
 	if len(ids) == 0 {
 		return nil, errors.New("must supply one or more identifiers")
 	}
@@ -68,7 +76,7 @@ func (c *Client) GetMulFaxStatus(ids []string) (*MulFaxStatus, error) {
 	}
 
 	result := mappedMulFaxStatus{}
-	if err := run(operation, &result, c.url); err != nil {
+	if err := runContext(ctx, operation, &result, c.url); err != nil {
 		return nil, err
 	}
 
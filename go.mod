@@ -1,5 +1,7 @@
 module github.com/mfridman/srfax
 
+go 1.13
+
 require (
 	github.com/mitchellh/mapstructure v0.0.0-20180715050151-f15292f7a699
 	github.com/pkg/errors v0.6.0
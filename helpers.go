@@ -2,11 +2,14 @@ package srfax
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -28,12 +31,22 @@ func (r *ResultError) Error() string { return fmt.Sprintf("%v: %v", r.Status, r.
 // sendPost is a wrapper around http.Post method.
 // Sends a JSON encoded request to SRFax and decodes the response body.
 func sendPost(r io.Reader, url string) (map[string]interface{}, error) {
+	return sendPostContext(context.Background(), r, url)
+}
+
+// sendPostContext is like sendPost, but the request is bound to ctx.
+func sendPostContext(ctx context.Context, r io.Reader, url string) (map[string]interface{}, error) {
 
 	client := http.Client{
 		Timeout: time.Duration(30 * time.Second),
 	}
 
-	resp, err := client.Post(url, "application/json", r)
+	req, err := newPostRequest(ctx, r, url)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed POST request")
 	}
@@ -60,6 +73,16 @@ func sendPost(r io.Reader, url string) (map[string]interface{}, error) {
 	return ms, nil
 }
 
+// newPostRequest builds a JSON encoded POST request to SRFax bound to ctx.
+func newPostRequest(ctx context.Context, r io.Reader, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build POST request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
 /*
 	The API returns a "Result" that, depending on action:
 		on success .. []interface{} or map[string]interface{} or []map[string]interface{}
@@ -202,7 +225,11 @@ func isNChars(s string, length int) bool {
 }
 
 func run(r io.Reader, resultType interface{}, url string) error {
-	msi, err := sendPost(r, url)
+	return runContext(context.Background(), r, resultType, url)
+}
+
+func runContext(ctx context.Context, r io.Reader, resultType interface{}, url string) error {
+	msi, err := sendPostContext(ctx, r, url)
 	if err != nil {
 		return errors.Wrap(err, "failed sendPost")
 	}
@@ -219,3 +246,59 @@ func constructReader(i interface{}) (io.Reader, error) {
 	}
 	return bytes.NewReader(by), nil
 }
+
+// writeJSONFile encodes v as indented JSON and atomically replaces the file at path.
+func writeJSONFile(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(f *os.File) error {
+		_, err := f.Write(b)
+		return err
+	})
+}
+
+// writeFileAtomic writes to a temporary file next to path and renames it over path once
+// write succeeded and the contents were synced, so readers never observe a partial file.
+func writeFileAtomic(path string, write func(f *os.File) error) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// appendJSONLine appends v as a single line of JSON to the file at path and syncs it, e.g.,
+// for audit logs.
+func appendJSONLine(path string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,516 @@
+package srfax
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// HotFolderJournalName is the name of the journal a HotFolder keeps in its directory.
+const HotFolderJournalName = ".srfax-hotfolder.json"
+
+// Directories of a hot folder that documents are moved to once their faxes completed.
+const (
+	HotFolderSent   = "sent"
+	HotFolderFailed = "failed"
+)
+
+// HotFolderOptions specify the sender and optional arguments for a HotFolder.
+type HotFolderOptions struct {
+	// Sender of the faxes, required
+	CallerID    int
+	SenderEmail string
+
+	// Options of every fax. AccountCode is replaced by a code identifying the document,
+	// which is how a fax queued just before a restart is found again
+	Queue QueueOptions
+
+	// Delay between polls, defaults to 30 seconds
+	Interval time.Duration
+
+	// Files modified within Settle are not sent yet, as they may still be being written,
+	// defaults to 10 seconds
+	Settle time.Duration
+
+	// How long a document whose QueueFax request did not complete is looked for in the
+	// outbox before it is sent again, defaults to 10 minutes
+	Grace time.Duration
+
+	// Optional callback invoked with each document moved to sent or failed
+	OnResult func(*HotFolderJob)
+
+	// Optional callback invoked with errors encountered while polling. The hot folder keeps
+	// running after an error
+	OnError func(error)
+}
+
+// HotFolderJob is a document dropped into a HotFolder. The journal holds the jobs in
+// progress, and a finished job is written next to its document as <name>.result.json.
+type HotFolderJob struct {
+	// Path of the document relative to the hot folder, with forward slashes
+	File   string
+	SHA256 string
+
+	// Recipients in sToFaxNumber wire format
+	To []string
+
+	// sAccountCode the document was queued with
+	AccountCode string
+
+	// When the document was picked up and when SRFax accepted it
+	Started time.Time
+	Queued  time.Time
+
+	// FaxDetailsIDs returned by QueueFax, one per recipient
+	IDs []int
+
+	// Latest status of each fax by FaxDetailsID
+	Results map[int]MulFaxStatusResult
+
+	// Why the document could not be queued
+	Error string `json:",omitempty"`
+
+	// Where the document was moved to, relative to the hot folder
+	Moved string `json:",omitempty"`
+}
+
+// Sent reports whether the document was delivered to all of its recipients.
+func (j *HotFolderJob) Sent() bool {
+	if j.Error != "" || len(j.IDs) == 0 {
+		return false
+	}
+	for _, id := range j.IDs {
+		if j.Results[id].SentStatus != "Sent" {
+			return false
+		}
+	}
+	return true
+}
+
+// done reports whether the job failed to queue or all of its faxes reached a final status.
+func (j *HotFolderJob) done() bool {
+	if j.Error != "" {
+		return true
+	}
+	if len(j.IDs) == 0 {
+		return false
+	}
+	for _, id := range j.IDs {
+		if !IsTerminalSentStatus(j.Results[id].SentStatus) {
+			return false
+		}
+	}
+	return true
+}
+
+// HotFolderSidecar is the optional <document>.json file naming the recipients of a document.
+type HotFolderSidecar struct {
+	// Fax numbers as accepted by ParseFaxNumber
+	To []string
+}
+
+// HotFolder faxes documents dropped into a directory. The recipient is named by the
+// subdirectory a document is dropped into, e.g., 6135551234/invoice.pdf, or by a sidecar
+// file next to it, e.g., invoice.pdf and invoice.pdf.json containing {"To": ["6135551234"]}.
+// Once all of its faxes completed a document is moved with its sidecar to the sent or
+// failed directory, keeping its relative path, along with a result file.
+//
+// Every document is recorded in a journal before it is queued, and a restarted hot folder
+// resumes tracking the faxes in the journal instead of sending them again. A document
+// whose QueueFax request did not complete is looked up in the outbox by its AccountCode,
+// and only sent again if it is still not found after the Grace period.
+type HotFolder struct {
+	c    *Client
+	dir  string
+	opts HotFolderOptions
+
+	journal *hotFolderJournal
+}
+
+type hotFolderJournal struct {
+	Jobs map[string]*HotFolderJob
+}
+
+// NewHotFolder returns a HotFolder for dir.
+func (c *Client) NewHotFolder(dir string, opts HotFolderOptions) *HotFolder {
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+	if opts.Settle <= 0 {
+		opts.Settle = 10 * time.Second
+	}
+	if opts.Grace <= 0 {
+		opts.Grace = 10 * time.Minute
+	}
+	return &HotFolder{c: c, dir: dir, opts: opts}
+}
+
+// Run polls the hot folder every Interval until ctx is cancelled, and returns ctx.Err().
+func (h *HotFolder) Run(ctx context.Context) error {
+	ticker := time.NewTicker(h.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := h.Poll(ctx, time.Now()); err != nil && ctx.Err() == nil && h.opts.OnError != nil {
+			h.opts.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll resolves documents whose QueueFax request did not complete, queues new documents,
+// and updates the status of queued faxes, moving documents whose faxes completed. It
+// returns the last error encountered, after processing all documents it could.
+func (h *HotFolder) Poll(ctx context.Context, now time.Time) error {
+	if h.opts.CallerID == 0 || h.opts.SenderEmail == "" {
+		return errors.New("hot folder requires CallerID and SenderEmail")
+	}
+	if h.journal == nil {
+		j, err := h.loadJournal()
+		if err != nil {
+			return err
+		}
+		h.journal = j
+	}
+
+	var lastErr error
+	dropped, err := h.resolve(ctx, now)
+	if err != nil {
+		lastErr = err
+	}
+	if err := h.scan(ctx, now, dropped); err != nil {
+		lastErr = err
+	}
+	if err := h.track(ctx); err != nil {
+		lastErr = err
+	}
+	return lastErr
+}
+
+func (h *HotFolder) loadJournal() (*hotFolderJournal, error) {
+	j := &hotFolderJournal{Jobs: make(map[string]*HotFolderJob)}
+	b, err := ioutil.ReadFile(filepath.Join(h.dir, HotFolderJournalName))
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read hot folder journal")
+	}
+	if err := json.Unmarshal(b, j); err != nil {
+		return nil, errors.Wrap(err, "failed to decode hot folder journal")
+	}
+	if j.Jobs == nil {
+		j.Jobs = make(map[string]*HotFolderJob)
+	}
+	return j, nil
+}
+
+func (h *HotFolder) saveJournal() error {
+	if err := writeJSONFile(filepath.Join(h.dir, HotFolderJournalName), h.journal); err != nil {
+		return errors.Wrap(err, "failed to save hot folder journal")
+	}
+	return nil
+}
+
+// resolve looks up jobs whose QueueFax request may or may not have reached SRFax in the
+// outbox. Jobs that are still not found after the Grace period are dropped from the journal
+// to be sent again, and returned so they are not sent again by the same Poll.
+func (h *HotFolder) resolve(ctx context.Context, now time.Time) (map[string]bool, error) {
+	var unresolved []string
+	since := now
+	for key, job := range h.journal.Jobs {
+		if job.Error != "" || len(job.IDs) > 0 {
+			continue
+		}
+		unresolved = append(unresolved, key)
+		if job.Started.Before(since) {
+			since = job.Started
+		}
+	}
+	if len(unresolved) == 0 {
+		return nil, nil
+	}
+	records, err := h.c.Faxes(ctx, FaxQuery{Direction: outbound, Since: since.Add(-time.Hour)}).All()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to look up unconfirmed documents in the outbox")
+	}
+
+	dropped := make(map[string]bool)
+	for _, key := range unresolved {
+		job := h.journal.Jobs[key]
+		for _, r := range records {
+			if r.AccountCode == job.AccountCode {
+				job.IDs = append(job.IDs, r.ID)
+			}
+		}
+		switch {
+		case len(job.IDs) > 0:
+			sort.Ints(job.IDs)
+			job.Queued = job.Started
+		case now.Sub(job.Started) >= h.opts.Grace:
+			delete(h.journal.Jobs, key)
+			dropped[key] = true
+		}
+	}
+	return dropped, h.saveJournal()
+}
+
+// scan queues the documents in the hot folder that are not in the journal yet, except the
+// dropped ones.
+func (h *HotFolder) scan(ctx context.Context, now time.Time, dropped map[string]bool) error {
+	var files []string
+	err := filepath.Walk(h.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(h.dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if strings.HasPrefix(info.Name(), ".") || rel == HotFolderSent || rel == HotFolderFailed {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || !info.Mode().IsRegular() || strings.HasSuffix(rel, ".json") {
+			return nil
+		}
+		if now.Sub(info.ModTime()) < h.opts.Settle {
+			return nil
+		}
+		if _, ok := h.journal.Jobs[rel]; !ok && !dropped[rel] {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to scan hot folder")
+	}
+
+	var lastErr error
+	for _, rel := range files {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := h.queue(rel, now); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// queue sends the document at rel. Documents that SRFax rejects, or that have no valid
+// recipient, are moved to the failed directory.
+func (h *HotFolder) queue(rel string, now time.Time) error {
+	path := filepath.Join(h.dir, filepath.FromSlash(rel))
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", rel)
+	}
+	sum := sha256.Sum256(b)
+	job := &HotFolderJob{File: rel, SHA256: hex.EncodeToString(sum[:]), Started: now}
+
+	to, err := h.recipients(rel)
+	if err != nil {
+		job.Error = err.Error()
+		return h.finish(job)
+	}
+	job.To = to
+	job.AccountCode = hotFolderAccountCode(job)
+
+	// record the job before sending, so a restart never sends it blindly again
+	h.journal.Jobs[rel] = job
+	if err := h.saveJournal(); err != nil {
+		delete(h.journal.Jobs, rel)
+		return err
+	}
+
+	opts := h.opts.Queue
+	opts.AccountCode = job.AccountCode
+	cfg := QueueCfg{CallerID: h.opts.CallerID, SenderEmail: h.opts.SenderEmail, FaxType: single, ToFaxNumber: to}
+	if len(to) > 1 {
+		cfg.FaxType = broadcast
+	}
+	files := []File{{Name: filepath.Base(path), Content: base64.StdEncoding.EncodeToString(b)}}
+	resp, err := h.c.QueueFax(files, cfg, opts)
+	if err != nil {
+		switch errors.Cause(err).(type) {
+		case *ResultError, *SuppressedError:
+			job.Error = err.Error()
+			return h.finish(job)
+		}
+		// the request may have reached SRFax, resolve the job from the outbox on the next poll
+		return errors.Wrapf(err, "failed to queue %s", rel)
+	}
+	for _, s := range strings.Split(resp.Result, "|") {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return errors.Errorf("failed to queue %s: unexpected FaxDetailsID %q", rel, s)
+		}
+		job.IDs = append(job.IDs, id)
+	}
+	job.Queued = time.Now()
+	return h.saveJournal()
+}
+
+// recipients returns the recipients of the document at rel, from its sidecar if present
+// and otherwise from the name of its top level directory.
+func (h *HotFolder) recipients(rel string) ([]string, error) {
+	var numbers []string
+	b, err := ioutil.ReadFile(filepath.Join(h.dir, filepath.FromSlash(rel)) + ".json")
+	switch {
+	case err == nil:
+		var sidecar HotFolderSidecar
+		if err := json.Unmarshal(b, &sidecar); err != nil {
+			return nil, errors.Wrap(err, "failed to decode sidecar")
+		}
+		numbers = sidecar.To
+	case !os.IsNotExist(err):
+		return nil, errors.Wrap(err, "failed to read sidecar")
+	case strings.Contains(rel, "/"):
+		numbers = []string{rel[:strings.Index(rel, "/")]}
+	}
+	if len(numbers) == 0 {
+		return nil, errors.New("no recipient: drop the document into a directory named by the fax number or add a sidecar file")
+	}
+	return ParseFaxNumbers(numbers...)
+}
+
+// hotFolderAccountCode returns the 20 character sAccountCode identifying a job.
+func hotFolderAccountCode(job *HotFolderJob) string {
+	sum := sha256.Sum256([]byte(job.File + "\x00" + job.SHA256 + "\x00" + strconv.FormatInt(job.Started.UnixNano(), 10)))
+	return hex.EncodeToString(sum[:])[:20]
+}
+
+// track updates the status of queued faxes and finishes the jobs that completed.
+func (h *HotFolder) track(ctx context.Context) error {
+	byID := make(map[int]*HotFolderJob)
+	var ids []string
+	for _, job := range h.journal.Jobs {
+		for _, id := range job.IDs {
+			if !IsTerminalSentStatus(job.Results[id].SentStatus) {
+				byID[id] = job
+				ids = append(ids, strconv.Itoa(id))
+			}
+		}
+	}
+	sort.Strings(ids)
+
+	var lastErr error
+	changed := false
+	for _, batch := range chunkStrings(ids, 50) {
+		resp, err := h.c.getMulFaxStatus(ctx, batch)
+		if err != nil {
+			lastErr = errors.Wrap(err, "failed to poll fax status")
+			continue
+		}
+		for _, res := range resp.Result {
+			id, err := IDFromName(res.FileName)
+			if err != nil || byID[id] == nil {
+				continue
+			}
+			job := byID[id]
+			if job.Results == nil {
+				job.Results = make(map[int]MulFaxStatusResult)
+			}
+			job.Results[id] = res
+			changed = true
+		}
+	}
+	if changed {
+		if err := h.saveJournal(); err != nil {
+			return err
+		}
+	}
+
+	keys := make([]string, 0, len(h.journal.Jobs))
+	for key, job := range h.journal.Jobs {
+		if job.done() {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := h.finish(h.journal.Jobs[key]); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// finish moves the document of a completed job and its sidecar to the sent or failed
+// directory, writes the result file next to it, and drops the job from the journal.
+func (h *HotFolder) finish(job *HotFolderJob) error {
+	target := HotFolderFailed
+	if job.Sent() {
+		target = HotFolderSent
+	}
+	src := filepath.Join(h.dir, filepath.FromSlash(job.File))
+	_, err := os.Stat(src)
+	srcExists := err == nil
+
+	// the destination is recorded before moving, so a restart finishes into the same place
+	if job.Moved == "" {
+		dst := filepath.Join(h.dir, target, filepath.FromSlash(job.File))
+		if _, err := os.Stat(dst); err == nil && srcExists {
+			// keep an earlier document of the same name
+			ext := filepath.Ext(dst)
+			dst = strings.TrimSuffix(dst, ext) + "-" + strconv.FormatInt(job.Started.Unix(), 10) + ext
+		}
+		moved, err := filepath.Rel(h.dir, dst)
+		if err != nil {
+			return err
+		}
+		job.Moved = filepath.ToSlash(moved)
+		if _, ok := h.journal.Jobs[job.File]; ok {
+			if err := h.saveJournal(); err != nil {
+				return err
+			}
+		}
+	}
+	dst := filepath.Join(h.dir, filepath.FromSlash(job.Moved))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return errors.Wrapf(err, "failed to move %s", job.File)
+	}
+	if srcExists {
+		if err := os.Rename(src, dst); err != nil {
+			return errors.Wrapf(err, "failed to move %s", job.File)
+		}
+	}
+	if err := os.Rename(src+".json", dst+".json"); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to move sidecar of %s", job.File)
+	}
+	if err := writeJSONFile(dst+".result.json", job); err != nil {
+		return errors.Wrapf(err, "failed to write result of %s", job.File)
+	}
+
+	if _, ok := h.journal.Jobs[job.File]; ok {
+		delete(h.journal.Jobs, job.File)
+		if err := h.saveJournal(); err != nil {
+			return err
+		}
+	}
+	if h.opts.OnResult != nil {
+		h.opts.OnResult(job)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package srfax
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHotFolder(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		nextID  = 500
+		queued  = make(map[string]int) // queue requests by file name
+		outbox  []map[string]interface{}
+		status  = make(map[string]string)
+		failOne = true
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		var resp map[string]interface{}
+		switch req["action"] {
+		case actionQueueFax:
+			name := req["sFileName_0"].(string)
+			queued[name]++
+			var ids []string
+			for _, to := range strings.Split(req["sToFaxNumber"].(string), "|") {
+				nextID++
+				id := strconv.Itoa(nextID)
+				ids = append(ids, id)
+				status[id] = "In Progress"
+				outbox = append(outbox, map[string]interface{}{
+					"FileName":    "20180101230101-8812-34_0|" + id,
+					"ToFaxNumber": to,
+					"AccountCode": req["sAccountCode"],
+					"EpochTime":   strconv.FormatInt(time.Now().Unix(), 10),
+				})
+			}
+			if name == "d.pdf" && failOne {
+				// queued, but the response is lost
+				failOne = false
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			resp = map[string]interface{}{"Status": "Success", "Result": strings.Join(ids, "|")}
+		case actionGetFaxOutbox:
+			resp = map[string]interface{}{"Status": "Success", "Result": outbox}
+		case actionGetMulFaxStatus:
+			var result []map[string]interface{}
+			for _, id := range strings.Split(req["sFaxDetailsID"].(string), "|") {
+				result = append(result, map[string]interface{}{"FileName": "20180101230101-8812-34_0|" + id, "SentStatus": status[id]})
+			}
+			resp = map[string]interface{}{"Status": "Success", "Result": result}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("6135551234/a.pdf", "a")
+	write("b.pdf", "b")
+	write("b.pdf.json", `{"To": ["613 555 1235", "613 555 1236"]}`)
+	write("c.pdf", "c")
+	write("6135551237/d.pdf", "d")
+
+	client := &Client{account: account{9090, "abc"}, url: srv.URL}
+	var results []*HotFolderJob
+	newHotFolder := func() *HotFolder {
+		return client.NewHotFolder(dir, HotFolderOptions{
+			CallerID:    6135550000,
+			SenderEmail: "fax@example.com",
+			OnResult:    func(j *HotFolderJob) { results = append(results, j) },
+		})
+	}
+	ctx := context.Background()
+	now := time.Now().Add(time.Minute)
+
+	h := newHotFolder()
+	if err := h.Poll(ctx, now); err == nil {
+		t.Fatal("want error of the lost queue response")
+	}
+	if len(results) != 1 || results[0].File != "c.pdf" || results[0].Error == "" {
+		t.Fatalf("want c.pdf failed without recipient; got %+v", results)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "failed", "c.pdf.result.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	// restarted before the faxes completed
+	mu.Lock()
+	status["501"] = "Sent"
+	status["502"] = "Sent"
+	status["503"] = "Failed"
+	status["504"] = "Sent"
+	mu.Unlock()
+	h = newHotFolder()
+	if err := h.Poll(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, n := range queued {
+		if n != 1 {
+			t.Errorf("want %s queued once; got %d", name, n)
+		}
+	}
+	if len(queued) != 3 {
+		t.Errorf("want 3 documents queued; got %v", queued)
+	}
+	for _, name := range []string{"sent/6135551234/a.pdf", "sent/6135551237/d.pdf", "failed/b.pdf", "failed/b.pdf.json", "failed/b.pdf.result.json"} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Error(err)
+		}
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "failed", "b.pdf.result.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job HotFolderJob
+	if err := json.Unmarshal(b, &job); err != nil {
+		t.Fatal(err)
+	}
+	if len(job.IDs) != 2 || job.Results[503].SentStatus != "Failed" || job.To[1] != "16135551236" || job.Moved != "failed/b.pdf" {
+		t.Fatalf("unexpected result %+v", job)
+	}
+	if len(h.journal.Jobs) != 0 {
+		t.Fatalf("want empty journal; got %+v", h.journal.Jobs)
+	}
+}
+
+func TestHotFolderGrace(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		queued int
+		lists  int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch req["action"] {
+		case actionQueueFax:
+			// the response is lost, and the fax never shows up in the outbox
+			queued++
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		case actionGetFaxOutbox:
+			lists++
+			json.NewEncoder(w).Encode(map[string]interface{}{"Status": "Success", "Result": []interface{}{}})
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a.pdf", "b.pdf"} {
+		path := filepath.Join(dir, "6135551234", name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := &Client{account: account{9090, "abc"}, url: srv.URL}
+	h := client.NewHotFolder(dir, HotFolderOptions{CallerID: 6135550000, SenderEmail: "fax@example.com", Grace: 10 * time.Minute})
+	ctx := context.Background()
+	now := time.Now().Add(time.Minute)
+
+	h.Poll(ctx, now)
+	if queued != 2 || lists != 0 {
+		t.Fatalf("want 2 queue requests and no outbox listing; got %d, %d", queued, lists)
+	}
+	h.Poll(ctx, now.Add(5*time.Minute))
+	if queued != 2 || lists != 1 {
+		t.Fatalf("want documents kept pending within the grace period and one listing per poll; got %d, %d", queued, lists)
+	}
+	h.Poll(ctx, now.Add(10*time.Minute))
+	if queued != 2 || len(h.journal.Jobs) != 0 {
+		t.Fatalf("want documents dropped after the grace period but not sent by the same poll; got %d, %+v", queued, h.journal.Jobs)
+	}
+	h.Poll(ctx, now.Add(11*time.Minute))
+	if queued != 4 {
+		t.Fatalf("want documents sent again by the next poll; got %d", queued)
+	}
+}
@@ -0,0 +1,189 @@
+package srfax
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// InboundFax is an unread inbound fax delivered to a ConsumeInbox handler.
+type InboundFax struct {
+	Record FaxRecord
+
+	// Decoded fax file contents, in the format requested by ConsumerOptions.FaxFormat
+	Document []byte
+
+	// Delivery attempt, starting at 1
+	Attempt int
+}
+
+// ConsumerOptions specify optional arguments for ConsumeInbox.
+type ConsumerOptions struct {
+	// Delay between polls of the unread inbox, defaults to 1 minute
+	Interval time.Duration
+
+	// PDF or TIFF, defaults to account settings if not supplied
+	FaxFormat string
+
+	// Number of times a fax is delivered before it is handed to DeadLetter, defaults to 5
+	MaxAttempts int
+
+	// Delay before a failed fax is delivered again, doubled after every failure.
+	// Defaults to 1 minute
+	RetryDelay time.Duration
+
+	// Optional callback invoked with a fax the handler failed to process MaxAttempts times,
+	// along with the last handler error. If DeadLetter returns nil the fax is marked as viewed,
+	// otherwise it is called again on the next poll. If DeadLetter is nil the fax is left
+	// unread and no longer delivered by this consumer
+	DeadLetter func(ctx context.Context, fax *InboundFax, err error) error
+
+	// Optional callback invoked with errors encountered while polling, retrieving or marking
+	// faxes as viewed. The consumer keeps polling after an error
+	OnError func(error)
+}
+
+// ConsumeInbox delivers every unread inbound fax to handler and marks it as viewed only after
+// handler returns nil. Faxes are delivered at least once: a fax is delivered again if handler
+// returns an error or if the fax could not be marked as viewed afterwards. Faxes are
+// delivered one at a time, oldest first.
+//
+// Delivery attempts are tracked in memory, a restarted consumer starts counting again.
+// ConsumeInbox polls until ctx is cancelled and returns ctx.Err().
+func (c *Client) ConsumeInbox(ctx context.Context, handler func(ctx context.Context, fax *InboundFax) error, opts ConsumerOptions) error {
+	if handler == nil {
+		return errors.New("handler cannot be nil")
+	}
+	con := newInboxConsumer(c, handler, opts)
+	ticker := time.NewTicker(con.opts.Interval)
+	defer ticker.Stop()
+	for {
+		con.poll(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+type inboxConsumer struct {
+	c       *Client
+	handler func(ctx context.Context, fax *InboundFax) error
+	opts    ConsumerOptions
+
+	failures map[string]*consumerFailure // by FaxFileName
+}
+
+type consumerFailure struct {
+	attempts int
+	next     time.Time
+	err      error
+	dead     bool // no DeadLetter hook, never delivered again
+}
+
+func newInboxConsumer(c *Client, handler func(ctx context.Context, fax *InboundFax) error, opts ConsumerOptions) *inboxConsumer {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = time.Minute
+	}
+	return &inboxConsumer{c: c, handler: handler, opts: opts, failures: make(map[string]*consumerFailure)}
+}
+
+func (con *inboxConsumer) onError(err error) {
+	if con.opts.OnError != nil {
+		con.opts.OnError(err)
+	}
+}
+
+// poll lists the unread inbox once and delivers every fax that is due at now.
+func (con *inboxConsumer) poll(ctx context.Context, now time.Time) {
+	records, err := con.c.Faxes(ctx, FaxQuery{Direction: inbound, ViewedStatus: "UNREAD"}).All()
+	if err != nil {
+		if ctx.Err() == nil {
+			con.onError(err)
+		}
+		return
+	}
+
+	unread := make(map[string]bool, len(records))
+	for _, r := range records {
+		unread[r.FileName] = true
+	}
+	// Faxes marked as viewed elsewhere no longer need tracking.
+	for name := range con.failures {
+		if !unread[name] {
+			delete(con.failures, name)
+		}
+	}
+
+	for _, r := range records {
+		if ctx.Err() != nil {
+			return
+		}
+		f := con.failures[r.FileName]
+		if f != nil && (f.dead || now.Before(f.next)) {
+			continue
+		}
+		if err := con.deliver(ctx, r, f, now); err != nil && ctx.Err() == nil {
+			con.onError(err)
+		}
+	}
+}
+
+// deliver retrieves a single fax and hands it to the handler, or to DeadLetter once the
+// handler has failed MaxAttempts times.
+func (con *inboxConsumer) deliver(ctx context.Context, r FaxRecord, f *consumerFailure, now time.Time) error {
+	dead := f != nil && f.attempts >= con.opts.MaxAttempts
+	if dead && con.opts.DeadLetter == nil {
+		f.dead = true
+		return nil
+	}
+	resp, err := con.c.retrieveFax(ctx, r.FileName, inbound, &RetrieveOptions{FaxFormat: con.opts.FaxFormat, MarkAsViewed: no})
+	if err != nil {
+		return errors.Wrapf(err, "failed to retrieve fax %s", r.FileName)
+	}
+	doc, err := resp.DecodeResult()
+	if err != nil {
+		return errors.Wrapf(err, "failed to decode fax %s", r.FileName)
+	}
+	fax := &InboundFax{Record: r, Document: doc, Attempt: 1}
+
+	if dead {
+		fax.Attempt = f.attempts
+		if err := con.opts.DeadLetter(ctx, fax, f.err); err != nil {
+			return errors.Wrapf(err, "dead letter failed for fax %s", r.FileName)
+		}
+		return con.markViewed(ctx, r.FileName)
+	}
+
+	if f != nil {
+		fax.Attempt = f.attempts + 1
+	}
+	if err := con.handler(ctx, fax); err != nil {
+		if f == nil {
+			f = &consumerFailure{}
+			con.failures[r.FileName] = f
+		}
+		f.attempts++
+		f.err = err
+		f.next = now.Add(con.opts.RetryDelay << uint(f.attempts-1))
+		return errors.Wrapf(err, "handler failed for fax %s, attempt %d", r.FileName, f.attempts)
+	}
+	return con.markViewed(ctx, r.FileName)
+}
+
+func (con *inboxConsumer) markViewed(ctx context.Context, name string) error {
+	cfg := ViewedStatusCfg{FaxFileName: name, Direction: inbound, MarkAsViewed: yes}
+	if _, err := con.c.updateViewedStatus(ctx, cfg); err != nil {
+		return errors.Wrapf(err, "failed to mark fax %s as viewed", name)
+	}
+	delete(con.failures, name)
+	return nil
+}
@@ -0,0 +1,115 @@
+package srfax
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInboxConsumer(t *testing.T) {
+
+	var (
+		mu     sync.Mutex
+		unread = map[string]bool{
+			"20180101000000-1-1_0|1": true,
+			"20180102000000-1-1_0|2": true,
+		}
+		retrieved []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		var result interface{}
+		switch req["action"] {
+		case actionGetFaxInbox:
+			if req["sViewedStatus"] != "UNREAD" {
+				t.Errorf("want UNREAD inbox query; got %v", req["sViewedStatus"])
+			}
+			faxes := make([]map[string]interface{}, 0)
+			for name := range unread {
+				faxes = append(faxes, map[string]interface{}{"FileName": name})
+			}
+			result = faxes
+		case actionRetrieveFax:
+			if req["sMarkasViewed"] != "N" {
+				t.Errorf("want retrieve without marking viewed; got %v", req["sMarkasViewed"])
+			}
+			name := req["sFaxFileName"].(string)
+			retrieved = append(retrieved, name)
+			result = base64.StdEncoding.EncodeToString([]byte("doc " + name))
+		case actionUpdateViewedStatus:
+			if req["sMarkasViewed"] == "Y" {
+				delete(unread, req["sFaxFileName"].(string))
+			}
+			result = "Message Updated Successfully"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Status": "Success", "Result": result})
+	}))
+	defer srv.Close()
+
+	client := &Client{account: account{9090, "abc"}, url: srv.URL}
+
+	var (
+		handled []string
+		dead    []string
+	)
+	handler := func(ctx context.Context, fax *InboundFax) error {
+		if string(fax.Document) != "doc "+fax.Record.FileName {
+			t.Errorf("unexpected document %q", fax.Document)
+		}
+		if fax.Record.ID == 2 {
+			return errors.New("cannot process")
+		}
+		handled = append(handled, fax.Record.FileName)
+		return nil
+	}
+	con := newInboxConsumer(client, handler, ConsumerOptions{
+		MaxAttempts: 2,
+		RetryDelay:  time.Minute,
+		DeadLetter: func(ctx context.Context, fax *InboundFax, err error) error {
+			if fax.Attempt != 2 || err == nil {
+				t.Errorf("unexpected dead letter attempt %d, err %v", fax.Attempt, err)
+			}
+			dead = append(dead, fax.Record.FileName)
+			return nil
+		},
+	})
+
+	now := time.Now()
+	ctx := context.Background()
+
+	// fax 1 succeeds and is marked viewed, fax 2 fails
+	con.poll(ctx, now)
+	if len(handled) != 1 || len(unread) != 1 {
+		t.Fatalf("want one fax handled and marked viewed; handled %v, unread %v", handled, unread)
+	}
+
+	// fax 2 is not retried before its delay elapsed
+	con.poll(ctx, now.Add(30*time.Second))
+	if len(retrieved) != 2 {
+		t.Fatalf("want no retry before RetryDelay; retrieved %v", retrieved)
+	}
+
+	// second failure, then handed to the dead letter hook after twice the delay
+	con.poll(ctx, now.Add(time.Minute))
+	con.poll(ctx, now.Add(3*time.Minute))
+	if len(dead) != 1 || dead[0] != "20180102000000-1-1_0|2" {
+		t.Fatalf("want fax 2 dead lettered; got %v", dead)
+	}
+	if len(unread) != 0 {
+		t.Fatalf("want dead lettered fax marked viewed; unread %v", unread)
+	}
+	if len(handled) != 1 {
+		t.Fatalf("want fax 1 handled once; got %v", handled)
+	}
+}
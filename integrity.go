@@ -0,0 +1,225 @@
+package srfax
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Integrity describes a downloaded fax file.
+type Integrity struct {
+	// Size of the file in bytes
+	Size int64
+
+	// Number of pages in the document
+	Pages int
+
+	// PDF or TIFF
+	Format string
+
+	// Hex encoded SHA-256 digest of the file
+	SHA256 string
+}
+
+// IntegrityError is returned when a fax file is malformed or does not match the expected
+// size or page count.
+type IntegrityError struct {
+	Reason string
+}
+
+func (e *IntegrityError) Error() string { return "integrity check failed: " + e.Reason }
+
+// CheckIntegrity verifies that the size bytes read from r hold a well-formed PDF or TIFF
+// document, counts its pages and computes its SHA-256 digest. Malformed documents return
+// an *IntegrityError. Use bytes.NewReader to check a document held in memory.
+func CheckIntegrity(r io.ReaderAt, size int64) (*Integrity, error) {
+	head := make([]byte, 8)
+	if _, err := r.ReadAt(head, 0); err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "failed to read document header")
+	}
+	out := &Integrity{Size: size}
+	var err error
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		out.Format = "PDF"
+		out.Pages, err = pdfPages(r, size)
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		out.Format = "TIFF"
+		out.Pages, err = tiffPages(r, size, head)
+	default:
+		return nil, &IntegrityError{Reason: "document is neither a PDF nor a TIFF"}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, size)); err != nil {
+		return nil, errors.Wrap(err, "failed to compute digest")
+	}
+	out.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return out, nil
+}
+
+// Verify checks the document against the Size and Pages of its inbox or outbox record.
+// Zero values are not checked.
+func (i *Integrity) Verify(size int64, pages int) error {
+	if size > 0 && i.Size != size {
+		return &IntegrityError{Reason: fmt.Sprintf("got %d bytes, want %d", i.Size, size)}
+	}
+	if pages > 0 && i.Pages != pages {
+		return &IntegrityError{Reason: fmt.Sprintf("got %d pages, want %d", i.Pages, pages)}
+	}
+	return nil
+}
+
+var (
+	pdfPageRe  = regexp.MustCompile(`/Type\s*/Page[^s]`)
+	pdfCountRe = regexp.MustCompile(`/Type\s*/Pages\b[^>]*/Count\s+(\d+)|/Count\s+(\d+)[^>]*/Type\s*/Pages\b`)
+)
+
+// pdfPages checks the PDF end-of-file marker and counts the page objects, scanning the
+// document in chunks. Falls back to the /Count of the page tree for documents that keep
+// their page objects in compressed object streams.
+func pdfPages(r io.ReaderAt, size int64) (int, error) {
+	tail := make([]byte, 1024)
+	if int64(len(tail)) > size {
+		tail = tail[:size]
+	}
+	if _, err := r.ReadAt(tail, size-int64(len(tail))); err != nil && err != io.EOF {
+		return 0, errors.Wrap(err, "failed to read document trailer")
+	}
+	if !bytes.Contains(tail, []byte("%%EOF")) {
+		return 0, &IntegrityError{Reason: "PDF is truncated, missing %%EOF marker"}
+	}
+
+	const chunk, overlap = 64 << 10, 256
+	var (
+		pages, count int
+		buf          = make([]byte, chunk+overlap)
+	)
+	for off := int64(0); off < size; off += chunk {
+		n, err := r.ReadAt(buf, off)
+		if err != nil && err != io.EOF {
+			return 0, errors.Wrap(err, "failed to read document")
+		}
+		b := buf[:n]
+		// matches starting in the overlap are counted with the next chunk
+		for _, m := range pdfPageRe.FindAllIndex(b, -1) {
+			if m[0] < chunk {
+				pages++
+			}
+		}
+		for _, m := range pdfCountRe.FindAllSubmatch(b, -1) {
+			for _, g := range m[1:] {
+				if v, err := strconv.Atoi(string(g)); err == nil && v > count {
+					count = v
+				}
+			}
+		}
+	}
+	if pages == 0 {
+		pages = count
+	}
+	if pages == 0 {
+		return 0, &IntegrityError{Reason: "PDF has no pages"}
+	}
+	return pages, nil
+}
+
+// tiffPages walks the chain of image file directories, one per page.
+func tiffPages(r io.ReaderAt, size int64, head []byte) (int, error) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if head[0] == 'M' {
+		order = binary.BigEndian
+	}
+	var (
+		pages int
+		buf   = make([]byte, 4)
+		seen  = make(map[int64]bool)
+		off   = int64(order.Uint32(head[4:8]))
+	)
+	for off != 0 {
+		if seen[off] || off < 8 || off+2 > size {
+			return 0, &IntegrityError{Reason: fmt.Sprintf("TIFF has an invalid directory offset %d", off)}
+		}
+		seen[off] = true
+		if _, err := r.ReadAt(buf[:2], off); err != nil {
+			return 0, errors.Wrap(err, "failed to read TIFF directory")
+		}
+		next := off + 2 + 12*int64(order.Uint16(buf[:2]))
+		if next+4 > size {
+			return 0, &IntegrityError{Reason: "TIFF is truncated"}
+		}
+		if _, err := r.ReadAt(buf, next); err != nil {
+			return 0, errors.Wrap(err, "failed to read TIFF directory")
+		}
+		pages++
+		off = int64(order.Uint32(buf))
+	}
+	if pages == 0 {
+		return 0, &IntegrityError{Reason: "TIFF has no pages"}
+	}
+	return pages, nil
+}
+
+// VerifyOptions specify the expected document when retrieving a fax with RetrieveFaxVerified.
+type VerifyOptions struct {
+	// Expected size in bytes, e.g., the Size of the inbox or outbox record.
+	// Only meaningful when retrieving the fax in the account's default format
+	ExpectSize int64
+
+	// Expected page count, e.g., the Pages of the inbox or outbox record
+	ExpectPages int
+
+	// Number of downloads before giving up on a mismatch, defaults to 3
+	Attempts int
+}
+
+// VerifyRecord returns VerifyOptions expecting the Size and Pages of r.
+func VerifyRecord(r FaxRecord) VerifyOptions {
+	return VerifyOptions{ExpectSize: int64(r.Size), ExpectPages: r.Pages}
+}
+
+// RetrieveFaxVerified downloads a fax to path like RetrieveFaxToFile and checks it with
+// CheckIntegrity against the expectations in vopts. A malformed or mismatching download is
+// retried up to vopts.Attempts times, the last *IntegrityError is returned if none succeeds.
+// path is only written once a download passed all checks.
+func (c *Client) RetrieveFaxVerified(ctx context.Context, ref FaxRef, path string, vopts VerifyOptions, options ...RetrieveOptions) (*Integrity, error) {
+	attempts := vopts.Attempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+	var (
+		integrity *Integrity
+		err       error
+	)
+	for i := 0; i < attempts; i++ {
+		err = writeFileAtomic(path, func(f *os.File) error {
+			n, err := c.RetrieveFaxTo(ctx, ref, f, options...)
+			if err != nil {
+				return err
+			}
+			if integrity, err = CheckIntegrity(f, n); err != nil {
+				return err
+			}
+			return integrity.Verify(vopts.ExpectSize, vopts.ExpectPages)
+		})
+		if _, ok := errors.Cause(err).(*IntegrityError); !ok || ctx.Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to retrieve fax %s", ref.Ident)
+	}
+	return integrity, nil
+}
@@ -0,0 +1,114 @@
+package srfax
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// testTIFF returns a little endian TIFF with n empty image file directories.
+func testTIFF(n int) []byte {
+	b := make([]byte, 8+n*18)
+	copy(b, "II*\x00")
+	binary.LittleEndian.PutUint32(b[4:], 8)
+	for i := 0; i < n; i++ {
+		off := 8 + i*18
+		binary.LittleEndian.PutUint16(b[off:], 1)
+		if i < n-1 {
+			binary.LittleEndian.PutUint32(b[off+14:], uint32(off+18))
+		}
+	}
+	return b
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	pdf, err := (&CoverPage{ToName: "Road Runner"}).PDF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tiff := testTIFF(3)
+
+	var tests = []struct {
+		name   string
+		doc    []byte
+		format string
+		pages  int
+		valid  bool
+	}{
+		{"pdf", pdf, "PDF", 1, true},
+		{"truncated pdf", pdf[:len(pdf)-20], "", 0, false},
+		{"tiff", tiff, "TIFF", 3, true},
+		{"truncated tiff", tiff[:len(tiff)-6], "", 0, false},
+		{"unknown", []byte("GIF89a"), "", 0, false},
+	}
+	for _, test := range tests {
+		got, err := CheckIntegrity(bytes.NewReader(test.doc), int64(len(test.doc)))
+		if !test.valid {
+			if _, ok := err.(*IntegrityError); !ok {
+				t.Fatalf("%s: want *IntegrityError; got %v", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got.Format != test.format || got.Pages != test.pages || got.Size != int64(len(test.doc)) || len(got.SHA256) != 64 {
+			t.Fatalf("%s: unexpected integrity %+v", test.name, got)
+		}
+	}
+}
+
+func TestRetrieveFaxVerified(t *testing.T) {
+	tiff := testTIFF(2)
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		doc := tiff
+		if atomic.AddInt32(&requests, 1) == 1 {
+			doc = tiff[:10] // corrupted first download
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Status": "Success", "Result": base64.StdEncoding.EncodeToString(doc)})
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fax.tif")
+
+	client := &Client{account: account{9090, "abc"}, url: srv.URL}
+	ref := FaxRef{Ident: "20180101230101-8812-34_0|31524120", Direction: "IN"}
+
+	got, err := client.RetrieveFaxVerified(context.Background(), ref, path, VerifyOptions{ExpectSize: int64(len(tiff)), ExpectPages: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 || got.Pages != 2 {
+		t.Fatalf("want 2 downloads of a 2 page fax; got %d downloads, %+v", requests, got)
+	}
+
+	os.Remove(path)
+	_, err = client.RetrieveFaxVerified(context.Background(), ref, path, VerifyOptions{ExpectPages: 5, Attempts: 2})
+	if _, ok := errors.Cause(err).(*IntegrityError); !ok {
+		t.Fatalf("want *IntegrityError for page mismatch; got %v", err)
+	}
+	if requests != 4 {
+		t.Fatalf("want 2 more downloads; got %d", requests-2)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("want no file after failed verification")
+	}
+}
@@ -0,0 +1,143 @@
+package srfax
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxNotificationSize bounds the body of a NotifyURL callback.
+const maxNotificationSize = 1 << 20
+
+// Notification is a fax status record POSTed by SRFax to the NotifyURL of a fax queued with
+// QueueFax or ForwardFax once the fax completes.
+type Notification struct {
+	// FaxDetailsID of the fax, parsed from its FileName
+	ID int
+
+	// Status record, in the same form as returned by GetFaxStatus
+	Status *FaxStatus
+
+	// Job ID the NotifyURL was signed with, blank unless received by a NotifySigner handler
+	JobID string
+}
+
+// NotificationHandler returns an http.Handler that receives NotifyURL callbacks, parses
+// them into a Notification and calls fn with the request context.
+//
+// SRFax documents that the fax status record is POSTed, with the fields of a GetFaxStatus
+// Result, but not how it is encoded, so both form encoded and JSON payloads are accepted.
+// The handler responds with 405 to anything but POST, with 400 to payloads that are not a
+// valid status record and with 500 if fn returns an error.
+func NotificationHandler(fn func(ctx context.Context, n *Notification) error) http.Handler {
+	return notificationHandler(nil, fn)
+}
+
+// notificationHandler implements NotificationHandler, verifying the NotifyURL token and
+// rejecting replays if s is not nil.
+func notificationHandler(s *NotifySigner, fn func(ctx context.Context, n *Notification) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var (
+			token, jobID string
+			expires      time.Time
+		)
+		if s != nil {
+			tokens := r.URL.Query()[NotifyTokenParam]
+			if len(tokens) == 1 {
+				token = tokens[0]
+			}
+			var err error
+			if jobID, expires, err = s.Verify(token, time.Now()); err != nil {
+				http.Error(w, "invalid token", http.StatusForbidden)
+				return
+			}
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxNotificationSize))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		n, err := ParseNotification(r.Header.Get("Content-Type"), body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		n.JobID = jobID
+
+		// A broadcast shares one NotifyURL between recipients, replays are detected per fax.
+		key := token + "/" + n.Status.Result.FileName
+		if s != nil {
+			// Reserved before fn runs, so concurrent deliveries of a notification are
+			// processed once, and released if fn fails so a retry is accepted.
+			ok, err := s.replay().Reserve(key, expires)
+			if err != nil {
+				http.Error(w, "failed to check replay", http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				http.Error(w, "notification already received", http.StatusConflict)
+				return
+			}
+		}
+		if err := fn(r.Context(), n); err != nil {
+			if s != nil {
+				s.replay().Release(key)
+			}
+			http.Error(w, "failed to process notification", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// ParseNotification parses and validates the body of a NotifyURL callback, given the
+// Content-Type of the request.
+func ParseNotification(contentType string, body []byte) (*Notification, error) {
+	var record map[string]interface{}
+	if strings.HasPrefix(contentType, "application/json") || bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		if err := json.Unmarshal(body, &record); err != nil {
+			return nil, errors.Wrap(err, "failed to decode JSON notification")
+		}
+	} else {
+		values, err := url.ParseQuery(strings.TrimSpace(string(body)))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode form notification")
+		}
+		record = make(map[string]interface{}, len(values))
+		for k := range values {
+			record[k] = values.Get(k)
+		}
+	}
+
+	// Decode the record as the Result of a Get_FaxStatus response.
+	msi := map[string]interface{}{"Status": "Success", "Result": record}
+	result := mappedFaxStatus{}
+	if err := decodeMap(msi, &result); err != nil {
+		return nil, errors.Wrap(err, "invalid notification")
+	}
+	if result.Result == nil {
+		return nil, errors.New("invalid notification: missing status record")
+	}
+	id, err := IDFromName(result.Result.FileName)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid notification FileName")
+	}
+	if result.Result.SentStatus == "" {
+		return nil, errors.New("invalid notification: missing SentStatus")
+	}
+
+	status := FaxStatus(result)
+	return &Notification{ID: id, Status: &status}, nil
+}
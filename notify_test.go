@@ -0,0 +1,80 @@
+package srfax
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNotificationHandler(t *testing.T) {
+
+	var got *Notification
+	var fail bool
+	h := NotificationHandler(func(ctx context.Context, n *Notification) error {
+		got = n
+		if fail {
+			return errors.New("oops")
+		}
+		return nil
+	})
+
+	// synthetic payloads, see testdata/README.md
+	var tests = []struct {
+		file        string
+		contentType string
+		sentStatus  string
+	}{
+		{"notify_form.txt", "application/x-www-form-urlencoded", "Sent"},
+		{"notify_json.json", "application/json", "Failed"},
+		{"notify_json.json", "", "Failed"},
+	}
+	for _, test := range tests {
+		b, err := ioutil.ReadFile(filepath.Join("testdata", test.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = nil
+		req := httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader(string(b)))
+		req.Header.Set("Content-Type", test.contentType)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: want 200; got %d %s", test.file, rec.Code, rec.Body)
+		}
+		if got == nil || got.ID != 31524120 {
+			t.Fatalf("%s: unexpected notification %+v", test.file, got)
+		}
+		r := got.Status.Result
+		if r.SentStatus != test.sentStatus || r.Pages != 2 || r.Size != 43210 || r.AccountCode != "inv-1042" || r.EpochTime != "1514847661" {
+			t.Fatalf("%s: unexpected status record %+v", test.file, r)
+		}
+	}
+
+	for _, test := range []struct {
+		method, body string
+		code         int
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "SentStatus=Sent", http.StatusBadRequest},
+		{http.MethodPost, "FileName=20180101230101-8812-34_0%7C1", http.StatusBadRequest},
+		{http.MethodPost, "{not json", http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(test.method, "/notify", strings.NewReader(test.body)))
+		if rec.Code != test.code {
+			t.Fatalf("%s %q: want %d; got %d", test.method, test.body, test.code, rec.Code)
+		}
+	}
+
+	fail = true
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader("FileName=1%7C1&SentStatus=Sent")))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("want 500 when the callback fails; got %d", rec.Code)
+	}
+}
@@ -0,0 +1,198 @@
+package srfax
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NotifyTokenParam is the query parameter of a signed NotifyURL holding the token.
+const NotifyTokenParam = "srfax_token"
+
+// NotifySigner authenticates NotifyURL callbacks. Before queuing, the NotifyURL is signed
+// with a job ID of the caller's choosing, e.g., an order or document ID. The token embedded
+// in the URL carries the job ID and an expiry, signed with HMAC-SHA256, so the callback can
+// be verified and correlated to its job without storing anything per fax.
+//
+// The same NotifySigner, or one with the same Key, must be used to sign and to receive.
+type NotifySigner struct {
+	// Secret key, at least 16 bytes
+	Key []byte
+
+	// How long a signed NotifyURL is accepted, defaults to 7 days
+	TTL time.Duration
+
+	// Records received notifications to reject replays, defaults to a MemoryReplayCache
+	Replay ReplayCache
+
+	once sync.Once
+}
+
+// ReplayCache records which notifications have been received. Implementations must be
+// safe for concurrent use.
+type ReplayCache interface {
+	// Reserve records key until expires, unless it is already recorded and has not yet
+	// expired. It reports whether key was recorded, atomically with respect to other calls.
+	Reserve(key string, expires time.Time) (bool, error)
+
+	// Release removes key, so a notification that failed to process is accepted again.
+	Release(key string) error
+}
+
+func (s *NotifySigner) replay() ReplayCache {
+	s.once.Do(func() {
+		if s.Replay == nil {
+			s.Replay = NewMemoryReplayCache()
+		}
+	})
+	return s.Replay
+}
+
+func (s *NotifySigner) validate() error {
+	if len(s.Key) < 16 {
+		return errors.New("Key must be at least 16 bytes")
+	}
+	return nil
+}
+
+func (s *NotifySigner) mac(payload string) []byte {
+	m := hmac.New(sha256.New, s.Key)
+	m.Write([]byte(payload))
+	return m.Sum(nil)
+}
+
+// Token returns a token for jobID that expires at expires.
+func (s *NotifySigner) Token(jobID string, expires time.Time) (string, error) {
+	if err := s.validate(); err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(jobID)) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload)), nil
+}
+
+// Verify checks the signature and expiry of token and returns the job ID it was issued for.
+func (s *NotifySigner) Verify(token string, now time.Time) (jobID string, expires time.Time, err error) {
+	if err := s.validate(); err != nil {
+		return "", time.Time{}, err
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", time.Time{}, errors.New("malformed token")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, s.mac(parts[0]+"."+parts[1])) {
+		return "", time.Time{}, errors.New("invalid token signature")
+	}
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, errors.New("malformed token expiry")
+	}
+	expires = time.Unix(exp, 0)
+	if !now.Before(expires) {
+		return "", time.Time{}, errors.New("token expired")
+	}
+	id, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", time.Time{}, errors.New("malformed token job ID")
+	}
+	return string(id), expires, nil
+}
+
+// SignURL adds a token for jobID to the query of notifyURL, expiring after TTL.
+func (s *NotifySigner) SignURL(notifyURL, jobID string) (string, error) {
+	u, err := url.Parse(notifyURL)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid NotifyURL")
+	}
+	if !(u.Scheme == "http" || u.Scheme == "https") || u.Host == "" {
+		return "", errors.New("NotifyURL must be an absolute http or https URL")
+	}
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = 7 * 24 * time.Hour
+	}
+	token, err := s.Token(jobID, time.Now().Add(ttl))
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set(NotifyTokenParam, token)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// SignQueueOptions returns a copy of opts with its NotifyURL signed for jobID, to be passed
+// to QueueFax. opts.NotifyURL must be set to the URL the Handler is served at.
+func (s *NotifySigner) SignQueueOptions(opts QueueOptions, jobID string) (QueueOptions, error) {
+	var err error
+	opts.NotifyURL, err = s.SignURL(opts.NotifyURL, jobID)
+	return opts, err
+}
+
+// SignForwardOptions is like SignQueueOptions, for ForwardFax.
+func (s *NotifySigner) SignForwardOptions(opts ForwardOptions, jobID string) (ForwardOptions, error) {
+	var err error
+	opts.NotifyURL, err = s.SignURL(opts.NotifyURL, jobID)
+	return opts, err
+}
+
+// Handler is like NotificationHandler, but only accepts callbacks to a NotifyURL signed by s.
+// The job ID of the token is set on the Notification. Requests with a missing, invalid or
+// expired token are rejected with 403, and a notification already received for the same
+// token and fax with 409.
+func (s *NotifySigner) Handler(fn func(ctx context.Context, n *Notification) error) http.Handler {
+	return notificationHandler(s, fn)
+}
+
+// MemoryReplayCache is a ReplayCache that keeps keys in memory until they expire.
+type MemoryReplayCache struct {
+	mu    sync.Mutex
+	keys  map[string]time.Time
+	calls int // Reserve calls since expired keys were swept
+}
+
+// Expired keys are swept from a MemoryReplayCache every replaySweepEvery calls to Reserve.
+const replaySweepEvery = 1000
+
+// NewMemoryReplayCache returns an empty MemoryReplayCache.
+func NewMemoryReplayCache() *MemoryReplayCache {
+	return &MemoryReplayCache{keys: make(map[string]time.Time)}
+}
+
+// Reserve implements ReplayCache.
+func (c *MemoryReplayCache) Reserve(key string, expires time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.calls++
+	if c.calls >= replaySweepEvery {
+		c.calls = 0
+		for k, exp := range c.keys {
+			if !now.Before(exp) {
+				delete(c.keys, k)
+			}
+		}
+	}
+	if exp, ok := c.keys[key]; ok && now.Before(exp) {
+		return false, nil
+	}
+	c.keys[key] = expires
+	return true, nil
+}
+
+// Release implements ReplayCache.
+func (c *MemoryReplayCache) Release(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.keys, key)
+	return nil
+}
@@ -0,0 +1,169 @@
+package srfax
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNotifySigner(t *testing.T) {
+	s := &NotifySigner{Key: []byte("0123456789abcdef")}
+
+	opts, err := s.SignQueueOptions(QueueOptions{NotifyURL: "https://example.com/notify?tenant=7"}, "order-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(opts.NotifyURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Query().Get("tenant") != "7" || u.Query().Get(NotifyTokenParam) == "" {
+		t.Fatalf("unexpected signed URL %s", opts.NotifyURL)
+	}
+
+	var got []*Notification
+	h := s.Handler(func(ctx context.Context, n *Notification) error {
+		got = append(got, n)
+		return nil
+	})
+	post := func(target, body string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	target := u.RequestURI()
+	first := "FileName=20180101230101-8812-34_0%7C100&SentStatus=Sent"
+	second := "FileName=20180101230101-8812-34_0%7C101&SentStatus=Failed"
+
+	if code := post(target, first); code != http.StatusOK {
+		t.Fatalf("want 200; got %d", code)
+	}
+	if len(got) != 1 || got[0].JobID != "order-42" || got[0].ID != 100 {
+		t.Fatalf("unexpected notification %+v", got)
+	}
+	// another recipient of the same broadcast shares the token
+	if code := post(target, second); code != http.StatusOK {
+		t.Fatalf("want 200 for another fax of the job; got %d", code)
+	}
+	if code := post(target, first); code != http.StatusConflict {
+		t.Fatalf("want 409 for a replay; got %d", code)
+	}
+
+	tampered := strings.Replace(target, "tenant=7", "tenant=7&"+NotifyTokenParam+"=x", 1)
+	for _, target := range []string{"/notify", "/notify?" + NotifyTokenParam + "=a.b.c", tampered} {
+		if code := post(target, first); code != http.StatusForbidden {
+			t.Fatalf("%s: want 403; got %d", target, code)
+		}
+	}
+
+	expired, err := s.Token("order-42", time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Verify(expired, time.Now()); err == nil {
+		t.Fatal("want error for expired token")
+	}
+	other := &NotifySigner{Key: []byte("fedcba9876543210")}
+	token, _ := s.Token("order-42", time.Now().Add(time.Hour))
+	if _, _, err := other.Verify(token, time.Now()); err == nil {
+		t.Fatal("want error for token signed with another key")
+	}
+	if _, err := (&NotifySigner{Key: []byte("short")}).SignURL("https://example.com", "x"); err == nil {
+		t.Fatal("want error for short key")
+	}
+}
+
+func TestNotifySignerConcurrentReplay(t *testing.T) {
+	s := &NotifySigner{Key: []byte("0123456789abcdef")}
+	signed, err := s.SignURL("https://example.com/notify", "order-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu    sync.Mutex
+		calls int
+		fail  = true
+		start = make(chan struct{})
+	)
+	h := s.Handler(func(ctx context.Context, n *Notification) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if fail {
+			fail = false
+			return context.DeadlineExceeded
+		}
+		return nil
+	})
+	post := func() int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, u.RequestURI(), strings.NewReader("FileName=20180101230101-8812-34_0%7C100&SentStatus=Sent"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// a failed notification is released, so the retry is processed
+	if code := post(); code != http.StatusInternalServerError {
+		t.Fatalf("want 500; got %d", code)
+	}
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 8)
+	for i := 0; i < cap(codes); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			codes <- post()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(codes)
+	ok := 0
+	for code := range codes {
+		switch code {
+		case http.StatusOK:
+			ok++
+		case http.StatusConflict:
+		default:
+			t.Fatalf("unexpected status %d", code)
+		}
+	}
+	if ok != 1 || calls != 2 {
+		t.Fatalf("want the retry processed once; got %d accepted, %d calls", ok, calls)
+	}
+}
+
+func TestMemoryReplayCache(t *testing.T) {
+	c := NewMemoryReplayCache()
+	if ok, _ := c.Reserve("a", time.Now().Add(-time.Second)); !ok {
+		t.Fatal("want first reservation")
+	}
+	if ok, _ := c.Reserve("a", time.Now().Add(time.Hour)); !ok {
+		t.Fatal("want an expired key reserved again before it is swept")
+	}
+	if ok, _ := c.Reserve("a", time.Now().Add(time.Hour)); ok {
+		t.Fatal("want a reserved key rejected")
+	}
+	for i := 0; i < replaySweepEvery; i++ {
+		c.Reserve(strconv.Itoa(i), time.Now().Add(-time.Second))
+	}
+	if len(c.keys) > replaySweepEvery {
+		t.Fatalf("want expired keys swept; got %d keys", len(c.keys))
+	}
+}
@@ -0,0 +1,189 @@
+package srfax
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultBroadcastBatchSize is the number of recipients sent in a single Queue_Fax
+	// request when BroadcastOptions.BatchSize is not supplied.
+	defaultBroadcastBatchSize = 50
+
+	// defaultBroadcastConcurrency is the number of Queue_Fax requests in flight at once
+	// when BroadcastOptions.Concurrency is not supplied.
+	defaultBroadcastConcurrency = 4
+)
+
+// BroadcastOptions specify how QueueBroadcast splits and sends a recipient list.
+type BroadcastOptions struct {
+	// Maximum number of recipients per Queue_Fax request, defaults to 50
+	BatchSize int
+
+	// Maximum number of Queue_Fax requests in flight at once, defaults to 4
+	Concurrency int
+}
+
+func (o *BroadcastOptions) validate() error {
+	if o.BatchSize < 0 {
+		return errors.New("BatchSize cannot be a negative number")
+	}
+	if o.Concurrency < 0 {
+		return errors.New("Concurrency cannot be a negative number")
+	}
+	if o.BatchSize == 0 {
+		o.BatchSize = defaultBroadcastBatchSize
+	}
+	if o.Concurrency == 0 {
+		o.Concurrency = defaultBroadcastConcurrency
+	}
+	return nil
+}
+
+// BroadcastResult maps every recipient of a QueueBroadcast to either the FaxDetailsID
+// returned by SRFax or the error encountered while queuing its batch.
+// A recipient appears in exactly one of IDs or Errors.
+type BroadcastResult struct {
+	IDs    map[string]string
+	Errors map[string]error
+}
+
+// Failed returns the sorted recipient numbers that could not be queued.
+func (r *BroadcastResult) Failed() []string {
+	out := make([]string, 0, len(r.Errors))
+	for n := range r.Errors {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Err returns nil if every recipient was queued, otherwise an error summarizing the failures.
+func (r *BroadcastResult) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return errors.Errorf("failed to queue %d of %d recipient(s): %s",
+		len(r.Errors), len(r.Errors)+len(r.IDs), strings.Join(r.Failed(), ", "))
+}
+
+func (r *BroadcastResult) merge(other *BroadcastResult) {
+	for n, id := range other.IDs {
+		r.IDs[n] = id
+	}
+	for n, err := range other.Errors {
+		r.Errors[n] = err
+	}
+}
+
+func (r *BroadcastResult) record(batch []string, resp *QueueFaxResp, err error) {
+	if err != nil {
+		for _, n := range batch {
+			r.Errors[n] = err
+		}
+		return
+	}
+	// A broadcast may return one FaxDetailsID per recipient separated by pipes,
+	// otherwise the single FaxDetailsID identifies the whole batch.
+	ids := strings.Split(resp.Result, "|")
+	for i, n := range batch {
+		if len(ids) == len(batch) {
+			r.IDs[n] = ids[i]
+		} else {
+			r.IDs[n] = resp.Result
+		}
+	}
+}
+
+// QueueBroadcast queues the same files to a large number of recipients. The numbers in
+// cfg.ToFaxNumber are split into batches of at most BatchSize numbers, each batch is sent
+// as a separate QueueFax operation, and at most Concurrency operations run at once.
+// cfg.FaxType is ignored and set per batch.
+//
+// Recipients on the client's SuppressionList are not sent to and are reported in
+// BroadcastResult.Errors with a *SuppressedError.
+//
+// The returned error is only non-nil when the arguments are invalid; failures of
+// individual batches are reported per recipient in BroadcastResult.Errors.
+func (c *Client) QueueBroadcast(files []File, cfg QueueCfg, bopts BroadcastOptions, options ...QueueOptions) (*BroadcastResult, error) {
+	if err := bopts.validate(); err != nil {
+		return nil, err
+	}
+	numbers := dedupeStrings(cfg.ToFaxNumber)
+	if len(numbers) == 0 {
+		return nil, errors.New("must supply one or more numbers in ToFaxNumber")
+	}
+	numbers, suppressed, err := c.filterSuppressed(numbers)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BroadcastResult{
+		IDs:    make(map[string]string, len(numbers)),
+		Errors: make(map[string]error),
+	}
+	for n, reason := range suppressed {
+		result.Errors[n] = &SuppressedError{Numbers: map[string]string{n: reason}}
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, bopts.Concurrency)
+	)
+	for _, batch := range chunkStrings(numbers, bopts.BatchSize) {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(batch []string) {
+			defer func() { <-sem; wg.Done() }()
+
+			bcfg := cfg
+			bcfg.ToFaxNumber = batch
+			bcfg.FaxType = broadcast
+			if len(batch) == 1 {
+				bcfg.FaxType = single
+			}
+			resp, err := c.QueueFax(files, bcfg, options...)
+
+			mu.Lock()
+			result.record(batch, resp, err)
+			mu.Unlock()
+		}(batch)
+	}
+	wg.Wait()
+
+	return result, nil
+}
+
+// chunkStrings splits ss into consecutive slices of at most size elements.
+func chunkStrings(ss []string, size int) [][]string {
+	if size <= 0 {
+		size = len(ss)
+	}
+	var chunks [][]string
+	for len(ss) > size {
+		chunks = append(chunks, ss[:size:size])
+		ss = ss[size:]
+	}
+	if len(ss) > 0 {
+		chunks = append(chunks, ss)
+	}
+	return chunks
+}
+
+// dedupeStrings returns ss without blank or repeated values, preserving order.
+func dedupeStrings(ss []string) []string {
+	seen := make(map[string]bool, len(ss))
+	out := make([]string, 0, len(ss))
+	for _, s := range ss {
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package srfax
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestChunkStrings(t *testing.T) {
+	var tests = []struct {
+		in   []string
+		size int
+		want int
+	}{
+		{[]string{"a", "b", "c", "d", "e"}, 2, 3},
+		{[]string{"a", "b", "c", "d"}, 2, 2},
+		{[]string{"a"}, 50, 1},
+		{[]string{}, 50, 0},
+		{[]string{"a", "b"}, 0, 1},
+	}
+	for _, test := range tests {
+		got := chunkStrings(test.in, test.size)
+		if len(got) != test.want {
+			t.Fatalf("chunkStrings(%v, %d) = %d chunks; want %d", test.in, test.size, len(got), test.want)
+		}
+		var n int
+		for _, c := range got {
+			n += len(c)
+		}
+		if n != len(test.in) {
+			t.Fatalf("chunkStrings(%v, %d) lost elements: got %d; want %d", test.in, test.size, n, len(test.in))
+		}
+	}
+}
+
+func TestQueueBroadcast(t *testing.T) {
+
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		n := atomic.AddInt32(&calls, 1)
+		numbers := strings.Split(req["sToFaxNumber"].(string), "|")
+
+		resp := map[string]interface{}{"Status": "Success", "Result": fmt.Sprintf("%d", 1000+n)}
+		if len(numbers) > 1 && req["sFaxType"] != broadcast {
+			resp = map[string]interface{}{"Status": "Failed", "Result": "Invalid Fax Type / "}
+		}
+		if strings.Contains(req["sToFaxNumber"].(string), "19999999999") {
+			resp = map[string]interface{}{"Status": "Failed", "Result": "Invalid Fax Number / "}
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	client := Client{account: account{9090, "abc"}, url: srv.URL}
+
+	cfg := QueueCfg{
+		CallerID:    6135551234,
+		SenderEmail: "test@example.com",
+		ToFaxNumber: []string{"16135550001", "16135550002", "16135550003", "16135550001", "19999999999"},
+	}
+	files := []File{{Name: "a.txt", Content: "aGVsbG8="}}
+
+	res, err := client.QueueBroadcast(files, cfg, BroadcastOptions{BatchSize: 2, Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("want 2 Queue_Fax calls; got %d", calls)
+	}
+	if len(res.IDs) != 2 {
+		t.Errorf("want 2 queued recipients; got %d: %v", len(res.IDs), res.IDs)
+	}
+	if got := res.Failed(); len(got) != 2 || got[1] != "19999999999" {
+		t.Errorf("want 2 failed recipients including 19999999999; got %v", got)
+	}
+	if res.Err() == nil {
+		t.Error("want non-nil Err when recipients failed")
+	}
+
+	if _, err := client.QueueBroadcast(files, QueueCfg{}, BroadcastOptions{}); err == nil {
+		t.Error("want error when no numbers supplied")
+	}
+}
@@ -72,7 +72,7 @@ type QueueCfg struct {
 	// SINGLE when sending to one number; BROADCAST when sending to multiple numbers
 	FaxType string
 
-	// Slice of string representing an 11 digit fax number
+	// Slice of string representing an 11 digit fax number, see ParseFaxNumbers
 	ToFaxNumber []string
 }
 
@@ -101,7 +101,13 @@ type mappedQueueFaxResp struct {
 // QueueFax adds fax item(s) to a queue for delivery.
 //
 // If Files is nil, the CoverPage option must be enabled. Otherwise will receive error: No Files to Fax
+//
+// If any of the recipients are on the client's SuppressionList nothing is sent and
+// a *SuppressedError is returned.
 func (c *Client) QueueFax(files []File, cfg QueueCfg, options ...QueueOptions) (*QueueFaxResp, error) {
+	if err := c.checkSuppressed(cfg.ToFaxNumber); err != nil {
+		return nil, err
+	}
 	opr := map[string]interface{}{
 		"action":       actionQueueFax,
 		"access_id":    c.AccessID,
@@ -0,0 +1,196 @@
+package srfax
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RangeSplit splits a long RANGE query into smaller date windows that are queried concurrently
+// and merged into a single response, as if one call had been made. It keeps individual
+// responses small when querying months or years of faxes.
+type RangeSplit struct {
+	// Length of each window in days, e.g., 1 for per-day or 7 for per-week windows.
+	// Zero disables splitting
+	Days int
+
+	// Maximum number of windows queried at once, defaults to 4
+	Concurrency int
+}
+
+func (s *RangeSplit) validate(period string) error {
+	if s.Days < 0 || s.Concurrency < 0 {
+		return errors.New("Split Days and Concurrency cannot be negative")
+	}
+	if s.Days > 0 && period != "RANGE" {
+		return errors.New("Split can only be used when Period is set to RANGE")
+	}
+	return nil
+}
+
+// windows returns the date windows covering startDate to endDate, both in YYYYMMDD format.
+func (s *RangeSplit) windows(startDate, endDate string) ([]dateWindow, error) {
+	const layout = "20060102"
+	start, err := time.Parse(layout, startDate)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid StartDate")
+	}
+	end, err := time.Parse(layout, endDate)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid EndDate")
+	}
+	return dateWindows(start, end, s.Days), nil
+}
+
+// run calls fn once for each window, with at most Concurrency calls in flight. The first error
+// cancels the context passed to the remaining calls and is returned.
+func (s *RangeSplit) run(ctx context.Context, windows []dateWindow, fn func(ctx context.Context, i int, w dateWindow) error) error {
+	n := s.Concurrency
+	if n <= 0 {
+		n = 4
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, n)
+	)
+	for i, w := range windows {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, w dateWindow) {
+			defer func() { <-sem; wg.Done() }()
+			if err := fn(ctx, i, w); err != nil {
+				once.Do(func() {
+					firstErr = errors.Wrapf(err, "failed window %s-%s", w.start, w.end)
+					cancel()
+				})
+			}
+		}(i, w)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// getFaxInboxSplit fetches an inbox RANGE query window by window and merges the results.
+func (c *Client) getFaxInboxSplit(ctx context.Context, opts *InboxOptions) (*Inbox, error) {
+	windows, err := opts.Split.windows(opts.StartDate, opts.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]*Inbox, len(windows))
+	err = opts.Split.run(ctx, windows, func(ctx context.Context, i int, w dateWindow) error {
+		o := *opts
+		o.StartDate, o.EndDate, o.Split = w.start, w.end, RangeSplit{}
+		var err error
+		parts[i], err = c.getFaxInbox(ctx, &o)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Inbox{Result: make([]InboxResult, 0)}
+	seen := make(map[string]bool)
+	for _, p := range parts {
+		out.Status = p.Status
+		for _, r := range p.Result {
+			if !seen[r.FileName] {
+				seen[r.FileName] = true
+				out.Result = append(out.Result, r)
+			}
+		}
+	}
+	return out, nil
+}
+
+// getFaxOutboxSplit fetches an outbox RANGE query window by window and merges the results.
+func (c *Client) getFaxOutboxSplit(ctx context.Context, opts *OutboxOptions) (*Outbox, error) {
+	windows, err := opts.Split.windows(opts.StartDate, opts.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]*Outbox, len(windows))
+	err = opts.Split.run(ctx, windows, func(ctx context.Context, i int, w dateWindow) error {
+		o := *opts
+		o.StartDate, o.EndDate, o.Split = w.start, w.end, RangeSplit{}
+		var err error
+		parts[i], err = c.getFaxOutbox(ctx, &o)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Outbox{Result: make([]OutboxResult, 0)}
+	seen := make(map[string]bool)
+	for _, p := range parts {
+		out.Status = p.Status
+		for _, r := range p.Result {
+			if !seen[r.FileName] {
+				seen[r.FileName] = true
+				out.Result = append(out.Result, r)
+			}
+		}
+	}
+	return out, nil
+}
+
+// getFaxUsageSplit fetches a usage RANGE query window by window and sums the number of faxes
+// and pages per user. The Period of each merged row is set to "StartDate-EndDate".
+func (c *Client) getFaxUsageSplit(ctx context.Context, opts *FaxUsageOptions) (*FaxUsage, error) {
+	windows, err := opts.Split.windows(opts.StartDate, opts.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]*FaxUsage, len(windows))
+	err = opts.Split.run(ctx, windows, func(ctx context.Context, i int, w dateWindow) error {
+		o := *opts
+		o.StartDate, o.EndDate, o.Split = w.start, w.end, RangeSplit{}
+		var err error
+		parts[i], err = c.getFaxUsage(ctx, &o)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		userID, subUserID int
+		billingNumber     string
+	}
+	out := &FaxUsage{}
+	index := make(map[key]int)
+	for _, p := range parts {
+		out.Status = p.Status
+		for _, r := range p.Result {
+			k := key{r.UserID, r.SubUserID, r.BillingNumber}
+			i, ok := index[k]
+			if !ok {
+				row := r
+				row.NumberOfFaxes, row.NumberOfPages = 0, 0
+				row.Period = opts.StartDate + "-" + opts.EndDate
+				out.Result = append(out.Result, row)
+				i = len(out.Result) - 1
+				index[k] = i
+			}
+			out.Result[i].NumberOfFaxes += r.NumberOfFaxes
+			out.Result[i].NumberOfPages += r.NumberOfPages
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,97 @@
+package srfax
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRangeSplit(t *testing.T) {
+
+	var (
+		mu      sync.Mutex
+		windows []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		start, end := req["sStartDate"].(string), req["sEndDate"].(string)
+		mu.Lock()
+		windows = append(windows, start+"-"+end)
+		mu.Unlock()
+
+		var result []map[string]interface{}
+		switch req["action"] {
+		case actionGetFaxUsage:
+			result = []map[string]interface{}{
+				{"Period": start, "UserID": 1, "BillingNumber": "a", "NumberOfFaxes": 2, "NumberOfPages": 5},
+				{"Period": start, "UserID": 1, "SubUserID": 2, "BillingNumber": "a", "NumberOfFaxes": 1, "NumberOfPages": 1},
+			}
+		default:
+			// every window returns its own fax plus one that is repeated across windows
+			result = []map[string]interface{}{
+				{"FileName": fmt.Sprintf("%s000000-1-1_0|%s", start, start)},
+				{"FileName": "20180101000000-1-1_0|1"},
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Status": "Success", "Result": result})
+	}))
+	defer srv.Close()
+
+	client := Client{account: account{9090, "abc"}, url: srv.URL}
+
+	t.Run("inbox", func(t *testing.T) {
+		windows = nil
+		inbox, err := client.GetFaxInbox(InboxOptions{Period: "RANGE", StartDate: "20180101", EndDate: "20180131", Split: RangeSplit{Days: 7, Concurrency: 2}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(windows) != 5 {
+			t.Fatalf("want 5 weekly windows; got %v", windows)
+		}
+		// 5 faxes unique to their window plus the repeated fax
+		if inbox.Total() != 6 {
+			t.Fatalf("want 6 deduplicated faxes; got %d", inbox.Total())
+		}
+		if inbox.Result[0].FileName != "20180101000000-1-1_0|20180101" || inbox.Result[5].FileName != "20180129000000-1-1_0|20180129" {
+			t.Fatalf("want results merged in window order; got %+v", inbox.Result)
+		}
+	})
+
+	t.Run("outbox", func(t *testing.T) {
+		outbox, err := client.GetFaxOutbox(OutboxOptions{Period: "RANGE", StartDate: "20180101", EndDate: "20180103", Split: RangeSplit{Days: 1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if outbox.Total() != 4 {
+			t.Fatalf("want 4 deduplicated faxes; got %d", outbox.Total())
+		}
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		usage, err := client.GetFaxUsage(FaxUsageOptions{Period: "RANGE", StartDate: "20180101", EndDate: "20180110", Split: RangeSplit{Days: 5}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(usage.Result) != 2 {
+			t.Fatalf("want 2 rows, one per user; got %+v", usage.Result)
+		}
+		if got := usage.Result[0]; got.NumberOfFaxes != 4 || got.NumberOfPages != 10 || got.Period != "20180101-20180110" {
+			t.Fatalf("unexpected merged row: %+v", got)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := client.GetFaxInbox(InboxOptions{Period: "ALL", Split: RangeSplit{Days: 7}}); err == nil {
+			t.Fatal("want error when splitting a non RANGE query")
+		}
+		if _, err := client.GetFaxUsage(FaxUsageOptions{Period: "RANGE", StartDate: "20180101", EndDate: "20180110", Split: RangeSplit{Days: -1}}); err == nil {
+			t.Fatal("want error for negative Days")
+		}
+	})
+}
@@ -0,0 +1,233 @@
+package srfax
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ReconcilerOptions specify optional arguments for a Reconciler.
+type ReconcilerOptions struct {
+	// How long to wait for a NotifyURL callback after a fax is tracked before polling its
+	// status, defaults to 30 minutes
+	Deadline time.Duration
+
+	// Delay between polls of overdue faxes, defaults to 1 minute
+	Interval time.Duration
+
+	// Maximum number of FaxDetailsIDs per GetMulFaxStatus request, defaults to 50
+	BatchSize int
+
+	// How long completed faxes are remembered to drop late or repeated callbacks,
+	// defaults to 24 hours
+	Retain time.Duration
+
+	// Optional callback invoked with errors encountered while polling or delivering.
+	// The reconciler keeps running after an error
+	OnError func(error)
+}
+
+// Reconciler guarantees a single final Notification per tracked fax, whether the NotifyURL
+// callback arrives or is lost. Callbacks are passed to Notify, e.g., by serving
+// NotificationHandler(r.Notify). Tracked faxes without a callback by the deadline are
+// polled with GetMulFaxStatus, and their final status is delivered as if it had been
+// received by callback.
+//
+// Deliveries are serialized per fax: a fax is delivered once deliver returns nil, and
+// retried from the next callback or poll if it returns an error.
+type Reconciler struct {
+	c       *Client
+	deliver func(ctx context.Context, n *Notification) error
+	opts    ReconcilerOptions
+
+	mu       sync.Mutex
+	pending  map[int]*trackedFax
+	inflight map[int]bool
+	done     map[int]time.Time // completed faxes and when they completed
+}
+
+type trackedFax struct {
+	jobID    string
+	deadline time.Time
+}
+
+// NewReconciler returns a Reconciler delivering final notifications to deliver.
+func (c *Client) NewReconciler(deliver func(ctx context.Context, n *Notification) error, opts ReconcilerOptions) *Reconciler {
+	if opts.Deadline <= 0 {
+		opts.Deadline = 30 * time.Minute
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+	if opts.Retain <= 0 {
+		opts.Retain = 24 * time.Hour
+	}
+	return &Reconciler{
+		c:        c,
+		deliver:  deliver,
+		opts:     opts,
+		pending:  make(map[int]*trackedFax),
+		inflight: make(map[int]bool),
+		done:     make(map[int]time.Time),
+	}
+}
+
+// Track starts tracking a fax queued with a NotifyURL, by the FaxDetailsID returned from
+// QueueFax or ForwardFax. jobID is set on the Notification if the callback does not carry
+// one, e.g., when the fax is reconciled by polling.
+func (r *Reconciler) Track(id int, jobID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.done[id]; ok || id <= 0 {
+		return
+	}
+	r.pending[id] = &trackedFax{jobID: jobID, deadline: time.Now().Add(r.opts.Deadline)}
+}
+
+// Pending returns the number of tracked faxes without a final notification.
+func (r *Reconciler) Pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pending)
+}
+
+// Notify handles a callback notification. Notifications for faxes that were already
+// delivered are dropped. Notifications of untracked faxes are delivered as well, e.g., for
+// faxes queued before a restart. An error is returned while another notification of the
+// same fax is being delivered, so the handler responds with 500 and SRFax retries the
+// callback in case that delivery fails.
+func (r *Reconciler) Notify(ctx context.Context, n *Notification) error {
+	if n.Status == nil || n.Status.Result == nil || !IsTerminalSentStatus(n.Status.Result.SentStatus) {
+		return nil
+	}
+	return r.finish(ctx, n)
+}
+
+// finish delivers a final notification unless the fax is already done. It fails while
+// another notification of the fax is in flight.
+func (r *Reconciler) finish(ctx context.Context, n *Notification) error {
+	r.mu.Lock()
+	if _, ok := r.done[n.ID]; ok {
+		r.mu.Unlock()
+		return nil
+	}
+	if r.inflight[n.ID] {
+		r.mu.Unlock()
+		return errors.Errorf("notification for fax %d is already being delivered", n.ID)
+	}
+	if t, ok := r.pending[n.ID]; ok && n.JobID == "" {
+		n.JobID = t.jobID
+	}
+	r.inflight[n.ID] = true
+	r.mu.Unlock()
+
+	err := r.deliver(ctx, n)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.inflight, n.ID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to deliver notification for fax %d", n.ID)
+	}
+	delete(r.pending, n.ID)
+	r.done[n.ID] = time.Now()
+	return nil
+}
+
+// Run polls overdue faxes every Interval until ctx is cancelled, and returns ctx.Err().
+func (r *Reconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Poll(ctx, time.Now()); err != nil && ctx.Err() == nil && r.opts.OnError != nil {
+			r.opts.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches the status of tracked faxes past their deadline at now and delivers those
+// that reached a terminal SentStatus. Completed faxes older than Retain are forgotten.
+func (r *Reconciler) Poll(ctx context.Context, now time.Time) error {
+	r.mu.Lock()
+	var ids []string
+	for id, t := range r.pending {
+		if !now.Before(t.deadline) && !r.inflight[id] {
+			ids = append(ids, strconv.Itoa(id))
+		}
+	}
+	for id, t := range r.done {
+		if now.Sub(t) > r.opts.Retain {
+			delete(r.done, id)
+		}
+	}
+	r.mu.Unlock()
+	sort.Strings(ids)
+
+	var lastErr error
+	for _, batch := range chunkStrings(ids, r.opts.BatchSize) {
+		resp, err := r.c.getMulFaxStatus(ctx, batch)
+		if err != nil {
+			lastErr = errors.Wrap(err, "failed to poll fax status")
+			continue
+		}
+		for _, res := range resp.Result {
+			if !IsTerminalSentStatus(res.SentStatus) {
+				continue
+			}
+			n, err := mulFaxNotification(res)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			if err := r.finish(ctx, n); err != nil {
+				lastErr = err
+			}
+		}
+	}
+	return lastErr
+}
+
+// mulFaxNotification converts a GetMulFaxStatus record to the Notification a callback would
+// have produced.
+func mulFaxNotification(res MulFaxStatusResult) (*Notification, error) {
+	id, err := IDFromName(res.FileName)
+	if err != nil {
+		return nil, err
+	}
+	record := map[string]interface{}{
+		"FileName":    res.FileName,
+		"SentStatus":  res.SentStatus,
+		"DateQueued":  res.DateQueued,
+		"DateSent":    res.DateSent,
+		"ToFaxNumber": res.ToFaxNumber,
+		"RemoteID":    res.RemoteID,
+		"ErrorCode":   res.ErrorCode,
+		"AccountCode": res.AccountCode,
+		"EpochTime":   res.EpochTime,
+	}
+	// blank numbers cannot be decoded as int, leave them zero
+	for k, v := range map[string]string{"Pages": res.Pages, "Duration": res.Duration, "Size": res.Size} {
+		if v != "" {
+			record[k] = v
+		}
+	}
+	msi := map[string]interface{}{"Status": "Success", "Result": record}
+	result := mappedFaxStatus{}
+	if err := decodeMap(msi, &result); err != nil {
+		return nil, errors.Wrapf(err, "failed to convert status of fax %d", id)
+	}
+	status := FaxStatus(result)
+	return &Notification{ID: id, Status: &status}, nil
+}
@@ -0,0 +1,142 @@
+package srfax
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReconciler(t *testing.T) {
+
+	var (
+		polls      int32
+		thirdState atomic.Value
+	)
+	thirdState.Store("In Progress")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&polls, 1)
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		var result []map[string]interface{}
+		for _, id := range strings.Split(req["sFaxDetailsID"].(string), "|") {
+			status := "Sent"
+			if id == "3" {
+				status = thirdState.Load().(string)
+			}
+			result = append(result, map[string]interface{}{"FileName": "20180101230101-8812-34_0|" + id, "SentStatus": status, "Pages": "2"})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Status": "Success", "Result": result})
+	}))
+	defer srv.Close()
+
+	client := &Client{account: account{9090, "abc"}, url: srv.URL}
+	delivered := make(map[int][]*Notification)
+	rec := client.NewReconciler(func(ctx context.Context, n *Notification) error {
+		delivered[n.ID] = append(delivered[n.ID], n)
+		return nil
+	}, ReconcilerOptions{Deadline: time.Minute})
+
+	rec.Track(1, "job-1")
+	rec.Track(2, "job-2")
+	rec.Track(3, "job-3")
+
+	ctx := context.Background()
+	callback := func(id string) {
+		t.Helper()
+		n, err := ParseNotification("", []byte("FileName=20180101230101-8812-34_0%7C"+id+"&SentStatus=Sent"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rec.Notify(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	callback("1")
+	callback("1")
+	if err := rec.Poll(ctx, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if polls != 0 {
+		t.Fatal("want no polling before the deadline")
+	}
+
+	// 2 and 3 missed their callbacks, only 2 has completed
+	now := time.Now().Add(2 * time.Minute)
+	if err := rec.Poll(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	callback("2")
+	if rec.Pending() != 1 {
+		t.Fatalf("want 1 fax pending; got %d", rec.Pending())
+	}
+
+	thirdState.Store("Failed")
+	if err := rec.Poll(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Pending() != 0 {
+		t.Fatalf("want no fax pending; got %d", rec.Pending())
+	}
+
+	for id := 1; id <= 3; id++ {
+		if len(delivered[id]) != 1 {
+			t.Fatalf("want exactly one notification for fax %d; got %d", id, len(delivered[id]))
+		}
+	}
+	if n := delivered[3][0]; n.JobID != "job-3" || n.Status.Result.SentStatus != "Failed" || n.Status.Result.Pages != 2 {
+		t.Fatalf("unexpected polled notification %+v %+v", n, n.Status.Result)
+	}
+	if delivered[1][0].JobID != "job-1" {
+		t.Fatal("want job ID of tracked fax set on callback notification")
+	}
+}
+
+func TestReconcilerInFlight(t *testing.T) {
+	var (
+		calls   int32
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	client := &Client{account: account{9090, "abc"}}
+	rec := client.NewReconciler(func(ctx context.Context, n *Notification) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+			return errors.New("downstream unavailable")
+		}
+		return nil
+	}, ReconcilerOptions{})
+
+	n, err := ParseNotification("", []byte("FileName=20180101230101-8812-34_0%7C9&SentStatus=Sent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	failed := make(chan error)
+	go func() { failed <- rec.Notify(ctx, n) }()
+	<-started
+
+	// a retried callback of the untracked fax must not be dropped while the first is in flight
+	if err := rec.Notify(ctx, n); err == nil {
+		t.Fatal("want error while a delivery is in flight")
+	}
+	close(release)
+	if err := <-failed; err == nil {
+		t.Fatal("want delivery error")
+	}
+	if err := rec.Notify(ctx, n); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("want the notification delivered on retry; got %d calls", calls)
+	}
+}
@@ -0,0 +1,294 @@
+package srfax
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RetentionPolicy specifies how long faxes are kept on SRFax, e.g., loaded with
+// LoadRetentionPolicy from a JSON file such as:
+//
+//	{
+//	  "InboundDays": 90,
+//	  "OutboundDays": 30,
+//	  "FailedOutboundDays": 365,
+//	  "ArchiveDir": "/srv/fax-archive",
+//	  "Holds": [
+//	    {"Name": "Smith v. Acme", "Numbers": ["613 555 0100"], "Expires": "2019-06-30T00:00:00Z"}
+//	  ]
+//	}
+type RetentionPolicy struct {
+	// Days inbound and outbound faxes are kept, zero keeps them forever
+	InboundDays  int
+	OutboundDays int
+
+	// Days outbound faxes with SentStatus Failed are kept, defaults to OutboundDays
+	FailedOutboundDays int
+
+	// Faxes under a legal hold are never deleted
+	Holds []LegalHold
+
+	// Directory faxes are archived to before they are deleted, named as by DownloadAll.
+	// A fax that cannot be archived and verified is not deleted. Blank deletes without
+	// archiving
+	ArchiveDir string
+
+	// PDF or TIFF, defaults to PDF
+	ArchiveFormat string
+
+	// How far back faxes are listed, defaults to 5 years
+	LookbackDays int
+
+	// Include faxes of sub users of the account
+	IncludeSubUsers bool
+
+	// Maximum number of faxes per DeleteFax request, defaults to 50
+	BatchSize int
+}
+
+// LegalHold exempts faxes from deletion. A hold applies to the faxes matching all of its
+// conditions, so a hold without conditions applies to all faxes.
+type LegalHold struct {
+	// Name of the matter, reported with held faxes
+	Name string
+
+	// Any of the FaxDetailsIDs
+	IDs []int `json:",omitempty"`
+
+	// Any of the sender or recipient numbers, compared ignoring formatting
+	Numbers []string `json:",omitempty"`
+
+	// Faxes received or sent within [From, Until), zero means unbounded
+	From, Until time.Time
+
+	// The hold is lifted at Expires, zero means never
+	Expires time.Time
+}
+
+func (h *LegalHold) match(r *FaxRecord, now time.Time) bool {
+	switch {
+	case !h.Expires.IsZero() && !now.Before(h.Expires),
+		len(h.IDs) > 0 && !containsInt(h.IDs, r.ID),
+		len(h.Numbers) > 0 && !anyNumber(h.Numbers, r.CallerID) && !anyNumber(h.Numbers, r.ToFaxNumber),
+		!h.From.IsZero() && r.Time.Before(h.From),
+		!h.Until.IsZero() && !r.Time.Before(h.Until):
+		return false
+	}
+	return true
+}
+
+func containsInt(ids []int, id int) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadRetentionPolicy reads a JSON RetentionPolicy from path.
+func LoadRetentionPolicy(path string) (*RetentionPolicy, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read retention policy")
+	}
+	p := &RetentionPolicy{}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, errors.Wrap(err, "failed to decode retention policy")
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *RetentionPolicy) validate() error {
+	if p.InboundDays < 0 || p.OutboundDays < 0 || p.FailedOutboundDays < 0 || p.LookbackDays < 0 || p.BatchSize < 0 {
+		return errors.New("retention days and BatchSize cannot be negative")
+	}
+	if p.ArchiveFormat != "" && p.ArchiveFormat != "PDF" && p.ArchiveFormat != "TIFF" {
+		return errors.New("ArchiveFormat must be PDF or TIFF")
+	}
+	return nil
+}
+
+// days returns the retention period of r in days, zero if it is kept forever.
+func (p *RetentionPolicy) days(r *FaxRecord) int {
+	if r.Direction == inbound {
+		return p.InboundDays
+	}
+	if r.Status == sentStatusFailed && p.FailedOutboundDays > 0 {
+		return p.FailedOutboundDays
+	}
+	return p.OutboundDays
+}
+
+// RetentionItem is a fax past its retention period.
+type RetentionItem struct {
+	Record FaxRecord
+
+	// Name of the legal hold keeping the fax
+	Hold string `json:",omitempty"`
+
+	// Archived file, relative to the ArchiveDir
+	Path string `json:",omitempty"`
+
+	// Why the fax was not deleted
+	Error string `json:",omitempty"`
+}
+
+// RetentionReport is the outcome of Purge.
+type RetentionReport struct {
+	Time   time.Time
+	DryRun bool
+
+	// Faxes deleted, or that would be deleted in a dry run
+	Deleted []RetentionItem
+
+	// Faxes kept by a legal hold
+	Held []RetentionItem
+
+	// Faxes that could not be archived or deleted
+	Failed []RetentionItem
+}
+
+// Err returns an error summarizing the failed faxes, or nil if there are none.
+func (r *RetentionReport) Err() error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+	return errors.Errorf("failed to purge %d fax(es), first error: %s: %s", len(r.Failed), r.Failed[0].Record.FileName, r.Failed[0].Error)
+}
+
+// Purge deletes the faxes past their retention period, except faxes under a legal hold and
+// outbound faxes still in progress. Faxes are archived first if ArchiveDir is set, and deleted
+// in batches. With dryRun nothing is archived or deleted, and the report lists what would be.
+//
+// An error is returned if the faxes could not be listed, otherwise failures are reported
+// per fax.
+func (c *Client) Purge(ctx context.Context, p RetentionPolicy, dryRun bool) (*RetentionReport, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	if p.ArchiveFormat == "" {
+		p.ArchiveFormat = "PDF"
+	}
+	if p.LookbackDays == 0 {
+		p.LookbackDays = 5 * 365
+	}
+	if p.BatchSize == 0 {
+		p.BatchSize = 50
+	}
+	now := time.Now()
+	report := &RetentionReport{Time: now, DryRun: dryRun}
+
+	for _, direction := range []string{inbound, outbound} {
+		shortest := p.InboundDays
+		if direction == outbound {
+			shortest = p.OutboundDays
+			if p.FailedOutboundDays > 0 && (shortest == 0 || p.FailedOutboundDays < shortest) {
+				shortest = p.FailedOutboundDays
+			}
+		}
+		if shortest == 0 {
+			continue
+		}
+		q := FaxQuery{
+			Direction:       direction,
+			Since:           now.AddDate(0, 0, -p.LookbackDays),
+			Until:           now.AddDate(0, 0, -shortest),
+			Window:          30 * 24 * time.Hour,
+			IncludeSubUsers: p.IncludeSubUsers,
+		}
+		records, err := c.Faxes(ctx, q).All()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list %s faxes", direction)
+		}
+
+		var expired []RetentionItem
+		for _, r := range records {
+			days := p.days(&r)
+			if days == 0 || r.Time.After(now.AddDate(0, 0, -days)) {
+				continue
+			}
+			if direction == outbound && !IsTerminalSentStatus(r.Status) {
+				continue
+			}
+			item := RetentionItem{Record: r}
+			for i := range p.Holds {
+				if p.Holds[i].match(&r, now) {
+					item.Hold = p.Holds[i].Name
+					break
+				}
+			}
+			if item.Hold != "" {
+				report.Held = append(report.Held, item)
+				continue
+			}
+			if p.ArchiveDir != "" {
+				item.Path = downloadName(r, p.ArchiveFormat)
+			}
+			expired = append(expired, item)
+		}
+
+		if dryRun {
+			report.Deleted = append(report.Deleted, expired...)
+			continue
+		}
+		c.purge(ctx, &p, direction, expired, report)
+	}
+	return report, nil
+}
+
+// purge archives and deletes the expired faxes of a direction, adding them to the report.
+func (c *Client) purge(ctx context.Context, p *RetentionPolicy, direction string, expired []RetentionItem, report *RetentionReport) {
+	var archived []RetentionItem
+	for _, item := range expired {
+		if item.Path != "" {
+			if err := c.archiveRecord(ctx, item.Record, filepath.Join(p.ArchiveDir, item.Path), p.ArchiveFormat); err != nil {
+				item.Error = "failed to archive: " + err.Error()
+				report.Failed = append(report.Failed, item)
+				continue
+			}
+		}
+		archived = append(archived, item)
+	}
+
+	for start := 0; start < len(archived); start += p.BatchSize {
+		end := start + p.BatchSize
+		if end > len(archived) {
+			end = len(archived)
+		}
+		batch := archived[start:end]
+		names := make([]string, len(batch))
+		for i, item := range batch {
+			names[i] = item.Record.FileName
+		}
+		if _, err := c.deleteFax(ctx, names, direction); err != nil {
+			for _, item := range batch {
+				item.Error = "failed to delete: " + err.Error()
+				report.Failed = append(report.Failed, item)
+			}
+			continue
+		}
+		report.Deleted = append(report.Deleted, batch...)
+	}
+}
+
+// archiveRecord retrieves r to path with its sidecar, named as by DownloadAll. A file already
+// at path, e.g., from DownloadAll or an interrupted Purge, is only kept if it passes
+// CheckIntegrity with the page count of r, otherwise the fax is retrieved again.
+func (c *Client) archiveRecord(ctx context.Context, r FaxRecord, path, format string) error {
+	if _, err := checkFile(path, r.Pages); err != nil {
+		if _, err := c.RetrieveFaxVerified(ctx, r.Ref(), path, VerifyOptions{ExpectPages: r.Pages}, RetrieveOptions{FaxFormat: format}); err != nil {
+			return err
+		}
+	}
+	_, err := c.downloadRecord(ctx, r, path, &DownloadOptions{FaxFormat: format, Verify: true})
+	return err
+}
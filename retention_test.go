@@ -0,0 +1,155 @@
+package srfax
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPurge(t *testing.T) {
+	pdf, err := (&CoverPage{ToName: "Road Runner"}).PDF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	daysAgo := func(days int) string {
+		return strconv.FormatInt(time.Now().AddDate(0, 0, -days).Unix(), 10)
+	}
+	inbox := []map[string]interface{}{
+		{"FileName": "20180101230101-8812-34_0|100", "CallerID": "6135550100", "EpochTime": daysAgo(100), "Pages": 1},
+		{"FileName": "20180101230101-8812-34_0|101", "CallerID": "6135550100", "EpochTime": daysAgo(10), "Pages": 1},
+		{"FileName": "20180101230101-8812-34_0|102", "CallerID": "6135550199", "EpochTime": daysAgo(100), "Pages": 1},
+		{"FileName": "20180101230101-8812-34_0|103", "CallerID": "6135550100", "EpochTime": daysAgo(120), "Pages": 1},
+	}
+	outbox := []map[string]interface{}{
+		{"FileName": "20180101230101-8812-34_0|200", "ToFaxNumber": "16135550100", "SentStatus": "Sent", "EpochTime": daysAgo(40), "Pages": 1},
+		{"FileName": "20180101230101-8812-34_0|201", "ToFaxNumber": "16135550100", "SentStatus": "Failed", "EpochTime": daysAgo(40), "Pages": 1},
+		{"FileName": "20180101230101-8812-34_0|202", "ToFaxNumber": "16135550100", "SentStatus": "Failed", "EpochTime": daysAgo(400), "Pages": 1},
+		{"FileName": "20180101230101-8812-34_0|203", "ToFaxNumber": "16135550100", "SentStatus": "In Progress", "EpochTime": daysAgo(40), "Pages": 1},
+	}
+	var (
+		mu        sync.Mutex
+		retrieves []string
+		deletes   [][]string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		var resp map[string]interface{}
+		switch req["action"] {
+		case actionGetFaxInbox:
+			resp = map[string]interface{}{"Status": "Success", "Result": inbox}
+		case actionGetFaxOutbox:
+			resp = map[string]interface{}{"Status": "Success", "Result": outbox}
+		case actionRetrieveFax:
+			name := req["sFaxFileName"].(string)
+			retrieves = append(retrieves, name)
+			if strings.HasSuffix(name, "|103") {
+				resp = map[string]interface{}{"Status": "Failed", "Result": "Temporarily unavailable"}
+				break
+			}
+			resp = map[string]interface{}{"Status": "Success", "Result": base64.StdEncoding.EncodeToString(pdf)}
+		case actionDeleteFax:
+			var names []string
+			for k, v := range req {
+				if strings.HasPrefix(k, "sFaxFileName_") {
+					names = append(names, v.(string))
+				}
+			}
+			sort.Strings(names)
+			deletes = append(deletes, names)
+			resp = map[string]interface{}{"Status": "Success", "Result": ""}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	client := &Client{account: account{9090, "abc"}, url: srv.URL}
+	policy := RetentionPolicy{
+		InboundDays:        90,
+		OutboundDays:       30,
+		FailedOutboundDays: 365,
+		Holds: []LegalHold{
+			{Name: "Smith v. Acme", Numbers: []string{"613 555 0199"}},
+			{Name: "expired", Expires: time.Now().Add(-time.Hour)},
+		},
+		ArchiveDir: dir,
+		BatchSize:  1,
+	}
+	ctx := context.Background()
+	ids := func(items []RetentionItem) []int {
+		var out []int
+		for _, item := range items {
+			out = append(out, item.Record.ID)
+		}
+		sort.Ints(out)
+		return out
+	}
+
+	report, err := client.Purge(ctx, policy, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(report.Deleted); len(got) != 4 || got[0] != 100 || got[1] != 103 || got[2] != 200 || got[3] != 202 {
+		t.Fatalf("want 100, 103, 200 and 202 to be deleted; got %v", got)
+	}
+	if len(report.Held) != 1 || report.Held[0].Record.ID != 102 || report.Held[0].Hold != "Smith v. Acme" {
+		t.Fatalf("want 102 held; got %+v", report.Held)
+	}
+	if len(retrieves) != 0 || len(deletes) != 0 {
+		t.Fatalf("want nothing archived or deleted in a dry run; got %v %v", retrieves, deletes)
+	}
+
+	// stale or truncated files at the archive path are not trusted
+	for _, item := range report.Deleted {
+		if id := item.Record.ID; id == 100 || id == 103 {
+			if err := ioutil.WriteFile(filepath.Join(dir, item.Path), pdf[:len(pdf)/2], 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	report, err = client.Purge(ctx, policy, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(report.Deleted); len(got) != 3 || got[0] != 100 || got[1] != 200 || got[2] != 202 {
+		t.Fatalf("want 100, 200 and 202 deleted; got %v", got)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Record.ID != 103 || report.Err() == nil {
+		t.Fatalf("want 103 failed to archive; got %+v", report.Failed)
+	}
+	if len(deletes) != 3 {
+		t.Fatalf("want 3 batches of 1; got %v", deletes)
+	}
+	for _, d := range deletes {
+		if strings.HasSuffix(d[0], "|103") {
+			t.Fatal("want fax kept when it could not be archived")
+		}
+	}
+	for _, item := range report.Deleted {
+		if _, err := checkFile(filepath.Join(dir, item.Path), item.Record.Pages); err != nil {
+			t.Error(err)
+		}
+	}
+}
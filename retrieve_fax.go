@@ -1,6 +1,7 @@
 package srfax
 
 import (
+	"context"
 	"encoding/base64"
 	"strconv"
 	"strings"
@@ -88,11 +89,15 @@ func (c *Client) RetrieveFax(ident, direction string, options ...RetrieveOptions
 	if len(options) > 0 {
 		opts = options[0]
 	}
+	return c.retrieveFax(context.Background(), ident, direction, &opts)
+}
+
+func (c *Client) retrieveFax(ctx context.Context, ident, direction string, opts *RetrieveOptions) (*RetrieveResp, error) {
 	if !(direction == inbound || direction == outbound) {
 		return nil, errors.Errorf("Direction must be one of: %s or %s", inbound, outbound)
 	}
 
-	opr, err := newRetrieveOperation(c, ident, direction, &opts)
+	opr, err := newRetrieveOperation(c, ident, direction, opts)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build a newRetrieveOperation")
 	}
@@ -103,7 +108,7 @@ func (c *Client) RetrieveFax(ident, direction string, options ...RetrieveOptions
 	}
 
 	result := mappedRetrieveResp{}
-	if err := run(operation, &result, c.url); err != nil {
+	if err := runContext(ctx, operation, &result, c.url); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,342 @@
+package srfax
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// FaxRef identifies a sent or received fax.
+type FaxRef struct {
+	// Either a FaxDetailsID or the full FaxFileName (including pipe and ID),
+	// returned from GetFaxInbox or GetFaxOutbox operation
+	Ident string
+
+	// IN or OUT for inbound or outbound fax
+	Direction string
+}
+
+func (r *FaxRef) validate() error {
+	if r.Ident == "" {
+		return errors.New("Ident cannot be empty")
+	}
+	if !(r.Direction == inbound || r.Direction == outbound) {
+		return errors.Errorf("Direction must be one of: %s or %s", inbound, outbound)
+	}
+	return nil
+}
+
+// RetrieveFaxTo is like RetrieveFax, but streams the decoded fax file into w instead of
+// holding the response in memory. It returns the number of bytes written to w.
+//
+// The request is bound to ctx only, unlike other operations it has no fixed timeout since
+// large faxes may take a while to download.
+//
+// If the response turns out to be a failure after part of the file was written, w may
+// contain partial contents. Use RetrieveFaxToFile to avoid that.
+func (c *Client) RetrieveFaxTo(ctx context.Context, ref FaxRef, w io.Writer, options ...RetrieveOptions) (int64, error) {
+	opts := RetrieveOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if err := ref.validate(); err != nil {
+		return 0, err
+	}
+
+	opr, err := newRetrieveOperation(c, ref.Ident, ref.Direction, &opts)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to build a newRetrieveOperation")
+	}
+	operation, err := constructReader(opr)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to construct a reader for newRetrieveOperation")
+	}
+	req, err := newPostRequest(ctx, operation, c.url)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed POST request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status: %v", resp.Status)
+	}
+	return decodeRetrieveStream(bufio.NewReader(resp.Body), w)
+}
+
+// RetrieveFaxToFile streams a fax file into a temporary file and atomically renames it to
+// path once the download succeeded, so path never holds a partial fax.
+// It returns the size of the file in bytes.
+func (c *Client) RetrieveFaxToFile(ctx context.Context, ref FaxRef, path string, options ...RetrieveOptions) (int64, error) {
+	var n int64
+	err := writeFileAtomic(path, func(f *os.File) error {
+		var err error
+		n, err = c.RetrieveFaxTo(ctx, ref, f, options...)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// maxMessageLen bounds the Status value and the part of a Result kept for error messages.
+const maxMessageLen = 1024
+
+// decodeRetrieveStream parses a Retrieve_Fax response of the form {"Status": ..., "Result": ...}
+// without buffering it, base64-decoding the Result string into w as it is read.
+func decodeRetrieveStream(br *bufio.Reader, w io.Writer) (int64, error) {
+	if c, err := skipSpace(br); err != nil || c != '{' {
+		return 0, errors.New("failed decoding response from POST: expecting JSON object")
+	}
+
+	var (
+		status               string
+		haveStatus, haveBody bool
+		message              bytes.Buffer // start of Result, reported if Status is not Success
+		n                    int64
+		decodeErr            error
+	)
+	for {
+		c, err := skipSpace(br)
+		if err != nil {
+			return n, errors.Wrap(err, "failed decoding response from POST")
+		}
+		if c == '}' {
+			break
+		}
+		if c == ',' {
+			if c, err = skipSpace(br); err != nil {
+				return n, errors.Wrap(err, "failed decoding response from POST")
+			}
+		}
+		if c != '"' {
+			return n, errors.Errorf("failed decoding response from POST: unexpected %q", c)
+		}
+		key, err := readJSONString(br, maxMessageLen)
+		if err != nil {
+			return n, errors.Wrap(err, "failed decoding response from POST")
+		}
+		if c, err := skipSpace(br); err != nil || c != ':' {
+			return n, errors.New("failed decoding response from POST: expecting colon")
+		}
+		if c, err = skipSpace(br); err != nil {
+			return n, errors.Wrap(err, "failed decoding response from POST")
+		}
+
+		switch {
+		case key == "Status" && c == '"':
+			if status, err = readJSONString(br, maxMessageLen); err != nil {
+				return n, errors.Wrap(err, "failed decoding response from POST")
+			}
+			haveStatus = true
+		case key == "Result" && c == '"':
+			haveBody = true
+			sr := &jsonStringReader{br: br}
+			if haveStatus && strings.ToLower(status) != "success" {
+				_, err = io.Copy(&message, io.LimitReader(sr, maxMessageLen))
+			} else {
+				sr.capture = &message
+				n, decodeErr = io.Copy(w, base64.NewDecoder(base64.StdEncoding, sr))
+			}
+			if err != nil {
+				return n, errors.Wrap(err, "failed decoding response from POST")
+			}
+			// drain whatever is left of the string after a decode error or a long message
+			if _, err := io.Copy(ioutil.Discard, sr); err != nil {
+				return n, errors.Wrap(err, "failed decoding response from POST")
+			}
+		default:
+			if err := skipJSONValue(br, c); err != nil {
+				return n, errors.Wrap(err, "failed decoding response from POST")
+			}
+		}
+	}
+
+	if !haveStatus || !haveBody {
+		return n, &ResultError{Status: "", Raw: `missing "Status" or "Result" key in response`}
+	}
+	if strings.ToLower(status) != "success" {
+		return n, &ResultError{Status: status, Raw: message.String()}
+	}
+	if decodeErr != nil {
+		return n, errors.Wrap(decodeErr, "could not decode base64 Result")
+	}
+	return n, nil
+}
+
+// jsonStringReader reads the unescaped contents of a JSON string whose opening quote has
+// already been consumed, and returns io.EOF at the closing quote. The first maxMessageLen
+// bytes are copied to capture.
+type jsonStringReader struct {
+	br      *bufio.Reader
+	capture *bytes.Buffer
+	pending []byte
+	done    bool
+}
+
+func (r *jsonStringReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.pending) > 0 {
+			m := copy(p[n:], r.pending)
+			r.pending = r.pending[m:]
+			n += m
+			continue
+		}
+		if r.done {
+			break
+		}
+		b, err := r.br.ReadByte()
+		if err != nil {
+			return n, unexpectedEOF(err)
+		}
+		switch b {
+		case '"':
+			r.done = true
+			continue
+		case '\\':
+			if r.pending, err = readEscape(r.br); err != nil {
+				return n, err
+			}
+			continue
+		}
+		p[n] = b
+		n++
+	}
+	if r.capture != nil && r.capture.Len() < maxMessageLen {
+		m := n
+		if room := maxMessageLen - r.capture.Len(); m > room {
+			m = room
+		}
+		r.capture.Write(p[:m])
+	}
+	if n == 0 && r.done {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// readEscape decodes the escape sequence following a backslash in a JSON string.
+func readEscape(br *bufio.Reader) ([]byte, error) {
+	b, err := br.ReadByte()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	switch b {
+	case '"', '\\', '/':
+		return []byte{b}, nil
+	case 'b':
+		return []byte{'\b'}, nil
+	case 'f':
+		return []byte{'\f'}, nil
+	case 'n':
+		return []byte{'\n'}, nil
+	case 'r':
+		return []byte{'\r'}, nil
+	case 't':
+		return []byte{'\t'}, nil
+	case 'u':
+		hex := make([]byte, 4)
+		if _, err := io.ReadFull(br, hex); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		v, err := strconv.ParseUint(string(hex), 16, 16)
+		if err != nil {
+			return nil, errors.Errorf("invalid escape \\u%s", hex)
+		}
+		buf := make([]byte, utf8.UTFMax)
+		return buf[:utf8.EncodeRune(buf, rune(v))], nil
+	}
+	return nil, errors.Errorf("invalid escape \\%c", b)
+}
+
+// readJSONString reads a JSON string whose opening quote has already been consumed.
+// Strings longer than limit are truncated.
+func readJSONString(br *bufio.Reader, limit int64) (string, error) {
+	var buf bytes.Buffer
+	sr := &jsonStringReader{br: br}
+	if _, err := io.Copy(&buf, io.LimitReader(sr, limit)); err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(ioutil.Discard, sr); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// skipJSONValue discards a JSON value whose first byte c has already been consumed.
+func skipJSONValue(br *bufio.Reader, c byte) error {
+	switch c {
+	case '"':
+		_, err := io.Copy(ioutil.Discard, &jsonStringReader{br: br})
+		return err
+	case '{', '[':
+		depth := 1
+		for depth > 0 {
+			b, err := br.ReadByte()
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			switch b {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			case '"':
+				if _, err := io.Copy(ioutil.Discard, &jsonStringReader{br: br}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	// number, true, false or null
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		switch b {
+		case ',', '}', ']', ' ', '\t', '\r', '\n':
+			return br.UnreadByte()
+		}
+	}
+}
+
+// skipSpace returns the next byte that is not JSON whitespace.
+func skipSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, nil
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package srfax
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestRetrieveFaxTo(t *testing.T) {
+
+	doc := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(doc)
+	// SRFax escapes forward slashes in the base64 Result, like PHP's json_encode
+	encoded := strings.Replace(base64.StdEncoding.EncodeToString(doc), "/", `\/`, -1)
+
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	client := &Client{account: account{9090, "abc"}, url: srv.URL}
+	ref := FaxRef{Ident: "20180101230101-8812-34_0|31524120", Direction: "IN"}
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		body = `{"Status":"Success", "Extra": {"a": ["}", 1]}, "Result":"` + encoded + `"}`
+		var buf bytes.Buffer
+		n, err := client.RetrieveFaxTo(ctx, ref, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(len(doc)) || !bytes.Equal(buf.Bytes(), doc) {
+			t.Fatalf("decoded %d bytes, want %d matching bytes", n, len(doc))
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		for _, b := range []string{
+			`{"Status":"Failed","Result":"Invalid File Name \/ ID"}`,
+			`{"Result":"Invalid File Name \/ ID", "Status":"Failed"}`,
+		} {
+			body = b
+			_, err := client.RetrieveFaxTo(ctx, ref, ioutil.Discard)
+			re, ok := errors.Cause(err).(*ResultError)
+			if !ok {
+				t.Fatalf("want *ResultError for %s; got %v", b, err)
+			}
+			if re.Status != "Failed" || re.Raw != "Invalid File Name / ID" {
+				t.Fatalf("unexpected ResultError: %+v", re)
+			}
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "srfax")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "fax.pdf")
+
+		body = `{"Status":"Success","Result":"` + encoded[:len(encoded)/2]
+		if _, err := client.RetrieveFaxToFile(ctx, ref, path); err == nil {
+			t.Fatal("want error for truncated response")
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatal("want no file after a failed download")
+		}
+
+		body = `{"Status":"Success","Result":"` + encoded + `"}`
+		if _, err := client.RetrieveFaxToFile(ctx, ref, path); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, doc) {
+			t.Fatal("file contents do not match")
+		}
+		if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+			t.Fatalf("want only the fax file left in dir; got %d files", len(files))
+		}
+	})
+}
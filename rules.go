@@ -0,0 +1,491 @@
+package srfax
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Rule action types.
+const (
+	// Save the fax into Dir, named as by DownloadAll
+	ActionSave = "save"
+	// Forward the fax to the To numbers with ForwardFax
+	ActionForward = "forward"
+	// Mark the fax as read
+	ActionMarkViewed = "mark-viewed"
+	// Delete the fax from SRFax, after all other actions succeeded
+	ActionDelete = "delete"
+	// POST the fax record and the names of the matched rules as JSON to URL
+	ActionWebhook = "webhook"
+)
+
+// RuleSet is an ordered list of routing rules for inbound faxes, e.g., loaded with
+// LoadRules from a JSON file such as:
+//
+//	{
+//	  "TimeZone": "America/Toronto",
+//	  "Rules": [
+//	    {
+//	      "Name": "after hours lab results",
+//	      "Match": {"CallerID": ["613 555 0100"], "Hours": "17:00-08:00"},
+//	      "Actions": [
+//	        {"Type": "forward", "To": ["613 555 0199"]},
+//	        {"Type": "save", "Dir": "/srv/faxes/lab"}
+//	      ],
+//	      "Final": true
+//	    },
+//	    {
+//	      "Name": "junk",
+//	      "Match": {"RemoteID": "(?i)^promo", "MaxPages": 1},
+//	      "Actions": [{"Type": "delete"}]
+//	    }
+//	  ]
+//	}
+type RuleSet struct {
+	// IANA time zone of the Hours and Weekdays conditions, defaults to the local time zone.
+	// Loading it needs the zoneinfo database, see AreaCodeLocations
+	TimeZone string
+
+	Rules []*Rule
+
+	loc *time.Location
+}
+
+// Rule applies its actions to every fax matching all of its conditions.
+type Rule struct {
+	Name    string
+	Match   RuleMatch
+	Actions []RuleAction
+
+	// Do not evaluate the rules following this one if it matches
+	Final bool
+}
+
+// RuleMatch holds the conditions of a Rule. Conditions left at their zero value match every fax.
+type RuleMatch struct {
+	// Any of the sender numbers, compared ignoring formatting
+	CallerID []string
+
+	// Regular expression matched against the RemoteID (CSID) of the sender
+	RemoteID string
+
+	// Any of the numbers or sub users the fax was received by
+	UserFaxNumber []string
+	UserID        []string
+
+	// Inclusive page count bounds, zero means unbounded
+	MinPages, MaxPages int
+
+	// Time of day the fax was received, HH:MM-HH:MM, e.g., 08:00-17:00. The period may wrap
+	// around midnight, e.g., 17:00-08:00
+	Hours string
+
+	// Days of the week the fax was received, e.g., ["Sat", "Sun"]
+	Weekdays []string
+
+	remoteID   *regexp.Regexp
+	start, end int // minutes after midnight
+	weekdays   map[time.Weekday]bool
+}
+
+// RuleAction is an action of a Rule, see the Action constants for the types.
+type RuleAction struct {
+	Type string
+
+	// save: directory and PDF or TIFF, defaults to PDF
+	Dir    string `json:",omitempty"`
+	Format string `json:",omitempty"`
+
+	// forward: recipients as accepted by ParseFaxNumber
+	To []string `json:",omitempty"`
+
+	// webhook: URL to POST to
+	URL string `json:",omitempty"`
+}
+
+// LoadRules reads and validates a JSON RuleSet from path.
+func LoadRules(path string) (*RuleSet, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read rules")
+	}
+	return ParseRules(b)
+}
+
+// ParseRules decodes and validates a JSON RuleSet.
+func ParseRules(b []byte) (*RuleSet, error) {
+	rs := &RuleSet{}
+	if err := json.Unmarshal(b, rs); err != nil {
+		return nil, errors.Wrap(err, "failed to decode rules")
+	}
+	if err := rs.compile(); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// compile validates the rules and prepares their conditions for matching.
+func (rs *RuleSet) compile() error {
+	rs.loc = time.Local
+	if rs.TimeZone != "" {
+		loc, err := time.LoadLocation(rs.TimeZone)
+		if err != nil {
+			return errors.Wrap(err, "invalid TimeZone")
+		}
+		rs.loc = loc
+	}
+	for i, rule := range rs.Rules {
+		if rule.Name == "" {
+			rule.Name = "rule " + strconv.Itoa(i+1)
+		}
+		if err := rule.compile(); err != nil {
+			return errors.Wrap(err, rule.Name)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) compile() error {
+	m := &r.Match
+	if m.RemoteID != "" {
+		re, err := regexp.Compile(m.RemoteID)
+		if err != nil {
+			return errors.Wrap(err, "invalid RemoteID")
+		}
+		m.remoteID = re
+	}
+	if m.Hours != "" {
+		var err error
+		if m.start, m.end, err = parseHours(m.Hours); err != nil {
+			return err
+		}
+	}
+	if len(m.Weekdays) > 0 {
+		m.weekdays = make(map[time.Weekday]bool)
+		for _, d := range m.Weekdays {
+			wd, ok := weekdays[strings.ToLower(d)]
+			if !ok {
+				return errors.Errorf("invalid weekday %q, must be one of Sun, Mon, Tue, Wed, Thu, Fri or Sat", d)
+			}
+			m.weekdays[wd] = true
+		}
+	}
+	if len(r.Actions) == 0 {
+		return errors.New("rule has no actions")
+	}
+	for i := range r.Actions {
+		a := &r.Actions[i]
+		switch a.Type {
+		case ActionSave:
+			if a.Dir == "" {
+				return errors.New("save action requires Dir")
+			}
+			if a.Format == "" {
+				a.Format = "PDF"
+			}
+			if a.Format != "PDF" && a.Format != "TIFF" {
+				return errors.New("save action Format must be PDF or TIFF")
+			}
+		case ActionForward:
+			to, err := ParseFaxNumbers(a.To...)
+			if err != nil || len(to) == 0 {
+				return errors.Errorf("forward action requires valid To numbers: %v", err)
+			}
+			a.To = to
+		case ActionWebhook:
+			if !strings.HasPrefix(a.URL, "http://") && !strings.HasPrefix(a.URL, "https://") {
+				return errors.New("webhook action requires an http or https URL")
+			}
+		case ActionMarkViewed, ActionDelete:
+		default:
+			return errors.Errorf("unknown action type %q", a.Type)
+		}
+	}
+	return nil
+}
+
+// parseHours parses a HH:MM-HH:MM period into minutes after midnight.
+func parseHours(s string) (start, end int, err error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return 0, 0, errors.Errorf("invalid Hours %q, must have format HH:MM-HH:MM", s)
+	}
+	var minutes [2]int
+	for i, p := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(p))
+		if err != nil {
+			return 0, 0, errors.Errorf("invalid Hours %q, must have format HH:MM-HH:MM", s)
+		}
+		minutes[i] = t.Hour()*60 + t.Minute()
+	}
+	return minutes[0], minutes[1], nil
+}
+
+// Match returns the rules matching r, in order, up to and including the first matching
+// Final rule.
+func (rs *RuleSet) Match(r *FaxRecord) []*Rule {
+	var matched []*Rule
+	for _, rule := range rs.Rules {
+		if !rule.Match.match(r, rs.loc) {
+			continue
+		}
+		matched = append(matched, rule)
+		if rule.Final {
+			break
+		}
+	}
+	return matched
+}
+
+func (m *RuleMatch) match(r *FaxRecord, loc *time.Location) bool {
+	if loc == nil {
+		loc = time.Local
+	}
+	t := r.Time.In(loc)
+	minute := t.Hour()*60 + t.Minute()
+	switch {
+	case len(m.CallerID) > 0 && !anyNumber(m.CallerID, r.CallerID),
+		len(m.UserFaxNumber) > 0 && !anyNumber(m.UserFaxNumber, r.UserFaxNumber),
+		len(m.UserID) > 0 && !containsString(m.UserID, r.UserID),
+		m.remoteID != nil && !m.remoteID.MatchString(strings.TrimSpace(r.RemoteID)),
+		m.MinPages > 0 && r.Pages < m.MinPages,
+		m.MaxPages > 0 && r.Pages > m.MaxPages,
+		m.weekdays != nil && !m.weekdays[t.Weekday()]:
+		return false
+	}
+	if m.Hours != "" {
+		if m.start <= m.end {
+			return minute >= m.start && minute < m.end
+		}
+		return minute >= m.start || minute < m.end
+	}
+	return true
+}
+
+func anyNumber(numbers []string, s string) bool {
+	for _, n := range numbers {
+		if sameNumber(n, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RouterOptions specify the sender of forwarded faxes.
+type RouterOptions struct {
+	// Sender of forwarded faxes, required by forward actions
+	CallerID    int
+	SenderEmail string
+
+	// Client used to invoke webhooks, defaults to a client with a 30 second timeout
+	HTTPClient *http.Client
+}
+
+// Router applies a RuleSet to inbound faxes, e.g., as the handler of a FaxWatcher.
+type Router struct {
+	c     *Client
+	rules *RuleSet
+	opts  RouterOptions
+}
+
+// NewRouter returns a Router applying rules.
+func (c *Client) NewRouter(rules *RuleSet, opts RouterOptions) *Router {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Router{c: c, rules: rules, opts: opts}
+}
+
+// RouteResult is the outcome of routing a fax.
+type RouteResult struct {
+	Record FaxRecord
+
+	// Names of the matched rules
+	Rules []string
+
+	// Actions of the matched rules, in the order they were applied
+	Actions []RouteAction
+}
+
+// RouteAction is an action applied to a fax.
+type RouteAction struct {
+	Rule   string
+	Action RuleAction
+
+	// Path of a saved fax
+	Path string `json:",omitempty"`
+
+	// Why the action failed, or why it was skipped
+	Error string `json:",omitempty"`
+}
+
+// Route applies the actions of the rules matching r. Delete actions are applied last, and
+// only if all other actions succeeded, so a fax is never deleted before it was saved or
+// forwarded. A fax is deleted and marked viewed at most once however many rules ask for it.
+//
+// All actions are attempted, and the last error is returned. Routing a fax again applies
+// all of its actions again.
+func (rt *Router) Route(ctx context.Context, r FaxRecord) (*RouteResult, error) {
+	res := rt.plan(r)
+	var lastErr error
+	for i := range res.Actions {
+		a := &res.Actions[i]
+		var err error
+		switch {
+		case a.Error != "":
+			// failed when planned, e.g., a forward without a CallerID
+			lastErr = errors.Errorf("%s: %s action failed for fax %d: %s", a.Rule, a.Action.Type, r.ID, a.Error)
+			continue
+		case a.Action.Type == ActionDelete && lastErr != nil:
+			err = errors.New("not deleted as another action failed")
+		default:
+			err = rt.apply(ctx, &res.Record, res.Rules, a)
+		}
+		if err != nil {
+			a.Error = err.Error()
+			lastErr = errors.Wrapf(err, "%s: %s action failed for fax %d", a.Rule, a.Action.Type, r.ID)
+		}
+	}
+	return res, lastErr
+}
+
+// DryRun lists the inbound faxes selected by q and reports the actions Route would apply
+// to each of them, without applying any.
+func (rt *Router) DryRun(ctx context.Context, q FaxQuery) ([]*RouteResult, error) {
+	q.Direction = inbound
+	records, err := rt.c.Faxes(ctx, q).All()
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*RouteResult, 0, len(records))
+	for _, r := range records {
+		results = append(results, rt.plan(r))
+	}
+	return results, nil
+}
+
+// plan returns the actions of the rules matching r in the order Route applies them.
+func (rt *Router) plan(r FaxRecord) *RouteResult {
+	res := &RouteResult{Record: r}
+	var deleteAction *RouteAction
+	viewed := false
+	for _, rule := range rt.rules.Match(&r) {
+		res.Rules = append(res.Rules, rule.Name)
+		for _, a := range rule.Actions {
+			switch a.Type {
+			case ActionDelete:
+				if deleteAction == nil {
+					deleteAction = &RouteAction{Rule: rule.Name, Action: a}
+				}
+				continue
+			case ActionMarkViewed:
+				if viewed {
+					continue
+				}
+				viewed = true
+			case ActionForward:
+				if rt.opts.CallerID == 0 || rt.opts.SenderEmail == "" {
+					res.Actions = append(res.Actions, RouteAction{Rule: rule.Name, Action: a, Error: "forwarding requires a CallerID and SenderEmail"})
+					continue
+				}
+			case ActionSave:
+				res.Actions = append(res.Actions, RouteAction{Rule: rule.Name, Action: a, Path: filepath.Join(a.Dir, downloadName(r, a.Format))})
+				continue
+			}
+			res.Actions = append(res.Actions, RouteAction{Rule: rule.Name, Action: a})
+		}
+	}
+	if deleteAction != nil {
+		for _, a := range res.Actions {
+			if a.Error != "" {
+				deleteAction.Error = "not deleted as another action failed"
+				break
+			}
+		}
+		res.Actions = append(res.Actions, *deleteAction)
+	}
+	return res
+}
+
+func (rt *Router) apply(ctx context.Context, r *FaxRecord, rules []string, a *RouteAction) error {
+	switch a.Action.Type {
+	case ActionSave:
+		if err := os.MkdirAll(a.Action.Dir, 0755); err != nil {
+			return err
+		}
+		_, err := rt.c.RetrieveFaxToFile(ctx, r.Ref(), a.Path, RetrieveOptions{FaxFormat: a.Action.Format})
+		return err
+	case ActionForward:
+		cfg := ForwardCfg{
+			FaxFileName: r.FileName,
+			Direction:   inbound,
+			CallerID:    rt.opts.CallerID,
+			SenderEmail: rt.opts.SenderEmail,
+			FaxType:     single,
+			ToFaxNumber: a.Action.To,
+		}
+		if len(a.Action.To) > 1 {
+			cfg.FaxType = broadcast
+		}
+		_, err := rt.c.forwardFax(ctx, cfg)
+		return err
+	case ActionMarkViewed:
+		_, err := rt.c.updateViewedStatus(ctx, ViewedStatusCfg{FaxFileName: r.FileName, Direction: inbound, MarkAsViewed: yes})
+		return err
+	case ActionDelete:
+		_, err := rt.c.deleteFax(ctx, []string{r.FileName}, inbound)
+		return err
+	case ActionWebhook:
+		return rt.webhook(ctx, a.Action.URL, r, rules)
+	}
+	return errors.Errorf("unknown action type %q", a.Action.Type)
+}
+
+// webhook POSTs {"Rules": [...], "Record": {...}} to url and expects a 2xx response.
+func (rt *Router) webhook(ctx context.Context, url string, r *FaxRecord, rules []string) error {
+	body, err := constructReader(struct {
+		Rules  []string
+		Record *FaxRecord
+	}{rules, r})
+	if err != nil {
+		return err
+	}
+	req, err := newPostRequest(ctx, body, url)
+	if err != nil {
+		return err
+	}
+	resp, err := rt.opts.HTTPClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed webhook request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected webhook status: %v", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package srfax
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testRules = `{
+  "TimeZone": "America/Toronto",
+  "Rules": [
+    {
+      "Name": "lab after hours",
+      "Match": {"CallerID": ["613-555-0100"], "Hours": "17:00-08:00"},
+      "Actions": [{"Type": "delete"}, {"Type": "forward", "To": ["613 555 0199"]}, {"Type": "save", "Dir": "%s"}],
+      "Final": true
+    },
+    {
+      "Name": "promo",
+      "Match": {"RemoteID": "(?i)^promo", "MaxPages": 1, "Weekdays": ["Sat", "Sun"]},
+      "Actions": [{"Type": "mark-viewed"}, {"Type": "webhook", "URL": "%s"}]
+    },
+    {
+      "Name": "everything",
+      "Actions": [{"Type": "mark-viewed"}]
+    }
+  ]
+}`
+
+func TestRuleSetMatch(t *testing.T) {
+	rs, err := ParseRules([]byte(`{"TimeZone": "America/Toronto", "Rules": [
+		{"Name": "night", "Match": {"CallerID": ["16135550100"], "Hours": "17:00-08:00"}, "Actions": [{"Type": "delete"}], "Final": true},
+		{"Name": "promo", "Match": {"RemoteID": "(?i)^promo", "MinPages": 2, "Weekdays": ["sat"]}, "Actions": [{"Type": "mark-viewed"}]},
+		{"Name": "all", "Actions": [{"Type": "mark-viewed"}]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	toronto, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Skip(err)
+	}
+	saturday := func(hour int) time.Time { return time.Date(2018, 1, 6, hour, 30, 0, 0, toronto) }
+
+	tt := []struct {
+		r    FaxRecord
+		want []string
+	}{
+		{FaxRecord{CallerID: "(613) 555-0100", Time: saturday(22)}, []string{"night"}},
+		{FaxRecord{CallerID: "6135550100", Time: saturday(7)}, []string{"night"}},
+		{FaxRecord{CallerID: "6135550100", Time: saturday(12)}, []string{"all"}},
+		{FaxRecord{RemoteID: " PROMO corp", Pages: 2, Time: saturday(12)}, []string{"promo", "all"}},
+		{FaxRecord{RemoteID: "promo", Pages: 1, Time: saturday(12)}, []string{"all"}},
+		{FaxRecord{RemoteID: "promo", Pages: 2, Time: saturday(12).AddDate(0, 0, 1)}, []string{"all"}},
+	}
+	for i, tc := range tt {
+		var got []string
+		for _, rule := range rs.Match(&tc.r) {
+			got = append(got, rule.Name)
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%d: want %v; got %v", i, tc.want, got)
+			continue
+		}
+		for j := range got {
+			if got[j] != tc.want[j] {
+				t.Errorf("%d: want %v; got %v", i, tc.want, got)
+			}
+		}
+	}
+
+	for _, s := range []string{
+		`{"Rules": [{"Match": {"Hours": "8-17"}, "Actions": [{"Type": "delete"}]}]}`,
+		`{"Rules": [{"Match": {"Weekdays": ["Someday"]}, "Actions": [{"Type": "delete"}]}]}`,
+		`{"Rules": [{"Match": {"RemoteID": "("}, "Actions": [{"Type": "delete"}]}]}`,
+		`{"Rules": [{"Actions": [{"Type": "forward", "To": ["123"]}]}]}`,
+		`{"Rules": [{"Actions": [{"Type": "print"}]}]}`,
+		`{"Rules": [{"Actions": []}]}`,
+	} {
+		if _, err := ParseRules([]byte(s)); err == nil {
+			t.Errorf("want error for %s", s)
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		actions  []string
+		webhooks []map[string]interface{}
+	)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		webhooks = append(webhooks, v)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		actions = append(actions, req["action"].(string))
+		mu.Unlock()
+		var resp map[string]interface{}
+		switch req["action"] {
+		case actionGetFaxInbox:
+			resp = map[string]interface{}{"Status": "Success", "Result": []map[string]interface{}{
+				{"FileName": "20180106230101-8812-34_0|100", "CallerID": "6135550100", "EpochTime": "1515279661", "Pages": 3},
+				{"FileName": "20180106170101-8812-34_0|101", "RemoteID": "Promo Inc", "EpochTime": "1515258061", "Pages": 1},
+			}}
+		case actionRetrieveFax:
+			resp = map[string]interface{}{"Status": "Success", "Result": base64.StdEncoding.EncodeToString([]byte("%PDF-1.4"))}
+		case actionForwardFax:
+			if req["sToFaxNumber"] != "16135550199" {
+				t.Errorf("unexpected forward to %v", req["sToFaxNumber"])
+			}
+			resp = map[string]interface{}{"Status": "Success", "Result": "200"}
+		default:
+			resp = map[string]interface{}{"Status": "Success", "Result": ""}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b, _ := json.Marshal(dir)
+	rs, err := ParseRules([]byte(fmt.Sprintf(testRules, b[1:len(b)-1], hook.URL)))
+	if err != nil {
+		t.Skip(err) // no time zone database
+	}
+	client := &Client{account: account{9090, "abc"}, url: srv.URL}
+	router := client.NewRouter(rs, RouterOptions{CallerID: 6135550000, SenderEmail: "fax@example.com"})
+	ctx := context.Background()
+
+	results, err := router.DryRun(ctx, FaxQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 1 || len(results) != 2 {
+		t.Fatalf("want a single inbox request and 2 results; got %v, %d results", actions, len(results))
+	}
+	promo, lab := results[0], results[1]
+	if a := lab.Actions; len(a) != 3 || a[0].Action.Type != ActionForward || a[2].Action.Type != ActionDelete {
+		t.Fatalf("want delete planned last; got %+v", a)
+	}
+	if a := promo.Actions; len(a) != 2 || promo.Rules[1] != "everything" {
+		t.Fatalf("want mark-viewed once and webhook; got %+v", promo)
+	}
+
+	actions = nil
+	for _, res := range results {
+		if _, err := router.Route(ctx, res.Record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{actionUpdateViewedStatus, actionForwardFax, actionRetrieveFax, actionDeleteFax}
+	if len(actions) != len(want) {
+		t.Fatalf("want %v; got %v", want, actions)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Fatalf("want %v; got %v", want, actions)
+		}
+	}
+	if _, err := os.Stat(lab.Actions[1].Path); err != nil {
+		t.Fatal(err)
+	}
+	if len(webhooks) != 1 || webhooks[0]["Record"].(map[string]interface{})["ID"].(float64) != 101 {
+		t.Fatalf("unexpected webhooks %v", webhooks)
+	}
+
+	// a failed save keeps the fax
+	os.RemoveAll(dir)
+	ioutil.WriteFile(dir, nil, 0644)
+	actions = nil
+	res, err := router.Route(ctx, lab.Record)
+	if err == nil || res.Actions[2].Error == "" {
+		t.Fatalf("want delete skipped; got %v %+v", err, res.Actions)
+	}
+	for _, a := range actions {
+		if a == actionDeleteFax {
+			t.Fatal("want fax not deleted")
+		}
+	}
+	if filepath.Base(res.Actions[1].Path) != "IN_20180106-230101_100_6135550100.pdf" {
+		t.Fatalf("unexpected path %s", res.Actions[1].Path)
+	}
+
+	// a forward that cannot be sent keeps the fax
+	os.Remove(dir)
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	noSender := client.NewRouter(rs, RouterOptions{})
+	actions = nil
+	res, err = noSender.Route(ctx, lab.Record)
+	if err == nil || res.Actions[0].Error == "" || res.Actions[2].Error == "" {
+		t.Fatalf("want forward failed and delete skipped; got %v %+v", err, res.Actions)
+	}
+	for _, a := range actions {
+		if a == actionDeleteFax || a == actionForwardFax {
+			t.Fatalf("want fax neither forwarded nor deleted; got %v", actions)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package srfax
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SendWindow is a daily window, in the recipient's local time, during which a recipient
+// may be faxed. The recipient's timezone is derived from the area code of the fax number,
+// see AreaCodeLocations for the timezone database this requires.
+//
+// For example, to only fax recipients between 8am and 9pm local time from an account
+// configured for Eastern time:
+//
+//	est, _ := time.LoadLocation("America/New_York")
+//	w := srfax.SendWindow{Start: 8 * time.Hour, End: 21 * time.Hour, Account: est}
+type SendWindow struct {
+	// Start and End of the window as an offset from local midnight. Start must be
+	// before End and both must be within the same day
+	Start, End time.Duration
+
+	// Timezone set on the SRFax account. SRFax interprets QueueFaxDate and QueueFaxTime
+	// in this timezone, so it is required
+	Account *time.Location
+
+	// Timezone assumed for international numbers and unknown area codes.
+	// If nil, such numbers are rejected
+	Fallback *time.Location
+}
+
+func (w *SendWindow) validate() error {
+	if w.Start < 0 || w.End > 24*time.Hour || w.Start >= w.End {
+		return errors.New("SendWindow must satisfy 0 <= Start < End <= 24h")
+	}
+	if w.Account == nil {
+		return errors.New("SendWindow Account location cannot be nil")
+	}
+	return nil
+}
+
+// Locations returns the timezone(s) the recipient of fax number s may be in.
+func (w *SendWindow) Locations(s string) ([]*time.Location, error) {
+	n, err := ParseFaxNumber(s)
+	if err != nil {
+		return nil, err
+	}
+	if n.IsNANP() {
+		locs, err := AreaCodeLocations(n.AreaCode())
+		if err == nil {
+			return locs, nil
+		}
+		if w.Fallback == nil {
+			return nil, err
+		}
+	}
+	if w.Fallback == nil {
+		return nil, errors.Errorf("cannot determine timezone of %s and no Fallback location supplied", n)
+	}
+	return []*time.Location{w.Fallback}, nil
+}
+
+// Next returns the earliest time at or after now, rounded up to the minute, that falls
+// within the window in every one of locs. Returns an error if the windows never overlap.
+func (w *SendWindow) Next(now time.Time, locs ...*time.Location) (time.Time, error) {
+	if err := w.validate(); err != nil {
+		return time.Time{}, err
+	}
+	t := now.Truncate(time.Minute)
+	if t.Before(now) {
+		t = t.Add(time.Minute)
+	}
+	// Each iteration either returns or moves t to the next window opening of at
+	// least one location, so a few days worth of iterations is sufficient.
+	for i := 0; i < 8*(len(locs)+1); i++ {
+		next := t
+		for _, loc := range locs {
+			if open := w.nextOpen(t, loc); open.After(next) {
+				next = open
+			}
+		}
+		if next.Equal(t) {
+			return t, nil
+		}
+		t = next
+	}
+	return time.Time{}, errors.New("send window does not overlap across the recipient's timezones")
+}
+
+// nextOpen returns t if it falls within the window in loc, otherwise the next time the window opens.
+func (w *SendWindow) nextOpen(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	y, m, d := local.Date()
+	at := func(day int, offset time.Duration) time.Time {
+		return time.Date(y, m, day, int(offset/time.Hour), int(offset%time.Hour/time.Minute), 0, 0, loc)
+	}
+	open, end := at(d, w.Start), at(d, w.End)
+	switch {
+	case t.Before(open):
+		return open
+	case t.Before(end):
+		return t
+	default:
+		return at(d+1, w.Start)
+	}
+}
+
+// Schedule returns the QueueFaxDate and QueueFaxTime, in the account's timezone, at which
+// fax number s may first be faxed at or after now. Both values are blank if the
+// recipient may be faxed immediately.
+func (w *SendWindow) Schedule(s string, now time.Time) (date, clock string, err error) {
+	locs, err := w.Locations(s)
+	if err != nil {
+		return "", "", err
+	}
+	at, err := w.Next(now, locs...)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to schedule %s", s)
+	}
+	return w.format(at, now)
+}
+
+func (w *SendWindow) format(at, now time.Time) (date, clock string, err error) {
+	if at.Sub(now) < time.Minute {
+		return "", "", nil
+	}
+	local := at.In(w.Account)
+	return local.Format("2006-01-02"), local.Format("15:04"), nil
+}
+
+// ScheduledQueue is one QueueFax operation produced by SendWindow.SplitBroadcast. Options
+// carries the QueueFaxDate and QueueFaxTime at which the recipients in Cfg may be faxed.
+type ScheduledQueue struct {
+	Cfg     QueueCfg
+	Options QueueOptions
+	SendAt  time.Time
+}
+
+// SplitBroadcast groups the recipients of cfg by timezone and returns one scheduled QueueFax
+// operation per group, ordered by send time. Each group's QueueFaxDate and QueueFaxTime
+// are set to the earliest time at or after now within the window, overriding any values
+// supplied in options. Recipients that may be faxed immediately have blank schedule values.
+func (w *SendWindow) SplitBroadcast(cfg QueueCfg, now time.Time, options ...QueueOptions) ([]ScheduledQueue, error) {
+	if err := w.validate(); err != nil {
+		return nil, err
+	}
+	opts := QueueOptions{}
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	type group struct {
+		numbers []string
+		at      time.Time
+	}
+	groups := make(map[string]*group)
+	keys := make([]string, 0)
+	for _, n := range dedupeStrings(cfg.ToFaxNumber) {
+		locs, err := w.Locations(n)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(locs))
+		for _, loc := range locs {
+			names = append(names, loc.String())
+		}
+		sort.Strings(names)
+		key := strings.Join(names, ",")
+
+		g, ok := groups[key]
+		if !ok {
+			at, err := w.Next(now, locs...)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to schedule %s", n)
+			}
+			g = &group{at: at}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+		g.numbers = append(g.numbers, n)
+	}
+
+	out := make([]ScheduledQueue, 0, len(keys))
+	for _, key := range keys {
+		g := groups[key]
+		sq := ScheduledQueue{Cfg: cfg, Options: opts, SendAt: g.at}
+		sq.Cfg.ToFaxNumber = g.numbers
+		sq.Cfg.FaxType = broadcast
+		if len(g.numbers) == 1 {
+			sq.Cfg.FaxType = single
+		}
+		sq.Options.QueueFaxDate, sq.Options.QueueFaxTime, _ = w.format(g.at, now)
+		out = append(out, sq)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].SendAt.Before(out[j].SendAt) })
+	return out, nil
+}
+
+// QueueInWindow queues files to every recipient in cfg.ToFaxNumber such that each recipient
+// is only faxed within the window in their local time. Recipients are grouped with
+// SplitBroadcast and each group is sent with QueueBroadcast.
+//
+// The returned error is only non-nil when the arguments are invalid or a recipient cannot
+// be scheduled, in which case nothing is queued. Failures of individual QueueFax operations,
+// or of a whole group, are reported per recipient so the groups already queued are kept.
+func (c *Client) QueueInWindow(files []File, cfg QueueCfg, w SendWindow, options ...QueueOptions) (*BroadcastResult, error) {
+	scheduled, err := w.SplitBroadcast(cfg, time.Now(), options...)
+	if err != nil {
+		return nil, err
+	}
+	result := &BroadcastResult{IDs: make(map[string]string), Errors: make(map[string]error)}
+	for _, sq := range scheduled {
+		res, err := c.QueueBroadcast(files, sq.Cfg, BroadcastOptions{}, sq.Options)
+		if err != nil {
+			for _, n := range sq.Cfg.ToFaxNumber {
+				result.Errors[n] = err
+			}
+			continue
+		}
+		result.merge(res)
+	}
+	return result, nil
+}
@@ -0,0 +1,127 @@
+package srfax
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAreaCodeLocations(t *testing.T) {
+	// every timezone in the table must load
+	for zone := range areaCodeZones {
+		if _, err := time.LoadLocation(zone); err != nil {
+			t.Fatalf("LoadLocation(%q): %v", zone, err)
+		}
+	}
+	for code := range areaCodeTimezones {
+		if !validNXX(code) {
+			t.Fatalf("area code %q in table is not a valid NANP area code", code)
+		}
+	}
+
+	var tests = []struct {
+		code string
+		want []string
+	}{
+		{"613", []string{"America/Toronto"}},
+		{"212", []string{"America/New_York"}},
+		{"602", []string{"America/Phoenix"}},
+		{"850", []string{"America/Chicago", "America/New_York"}},
+	}
+	for _, test := range tests {
+		locs, err := AreaCodeLocations(test.code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make(map[string]bool)
+		for _, l := range locs {
+			names[l.String()] = true
+		}
+		if len(names) != len(test.want) {
+			t.Fatalf("AreaCodeLocations(%q) = %v; want %v", test.code, locs, test.want)
+		}
+		for _, w := range test.want {
+			if !names[w] {
+				t.Fatalf("AreaCodeLocations(%q) = %v; want %v", test.code, locs, test.want)
+			}
+		}
+	}
+	if _, err := AreaCodeLocations("999"); err == nil {
+		t.Fatal("want error for unknown area code")
+	}
+}
+
+func TestSendWindowSchedule(t *testing.T) {
+	est, _ := time.LoadLocation("America/New_York")
+	w := SendWindow{Start: 8 * time.Hour, End: 21 * time.Hour, Account: est}
+
+	var tests = []struct {
+		number    string
+		now       time.Time
+		wantDate  string
+		wantClock string
+	}{
+		// 10am Eastern, Toronto recipient is within window
+		{"16135551234", time.Date(2018, 3, 1, 10, 0, 0, 0, est), "", ""},
+		// 10am Eastern is 7am Pacific, wait until 8am Pacific (11am Eastern)
+		{"14155551234", time.Date(2018, 3, 1, 10, 0, 0, 0, est), "2018-03-01", "11:00"},
+		// 11:30pm Eastern, wait until 8am Eastern the next day
+		{"12125551234", time.Date(2018, 3, 1, 23, 30, 0, 0, est), "2018-03-02", "08:00"},
+		// 8:30am Eastern is 7:30am Central, 850 spans both so wait for Central
+		{"18505551234", time.Date(2018, 3, 1, 8, 30, 0, 0, est), "2018-03-01", "09:00"},
+	}
+	for _, test := range tests {
+		date, clock, err := w.Schedule(test.number, test.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if date != test.wantDate || clock != test.wantClock {
+			t.Fatalf("Schedule(%q, %v) = %q %q; want %q %q", test.number, test.now, date, clock, test.wantDate, test.wantClock)
+		}
+	}
+
+	if _, _, err := w.Schedule("+44 20 7946 0000", time.Now()); err == nil {
+		t.Fatal("want error for international number without Fallback")
+	}
+	w.Fallback = est
+	if _, _, err := w.Schedule("+44 20 7946 0000", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := (&SendWindow{Start: 9 * time.Hour, End: 8 * time.Hour, Account: est}).Schedule("12125551234", time.Now()); err == nil {
+		t.Fatal("want error for invalid window")
+	}
+}
+
+func TestSendWindowSplitBroadcast(t *testing.T) {
+	est, _ := time.LoadLocation("America/New_York")
+	w := SendWindow{Start: 8 * time.Hour, End: 21 * time.Hour, Account: est}
+	now := time.Date(2018, 3, 1, 10, 0, 0, 0, est)
+
+	cfg := QueueCfg{
+		CallerID:    6135551234,
+		SenderEmail: "test@example.com",
+		FaxType:     broadcast,
+		ToFaxNumber: []string{"14155551234", "12125551234", "13105551234", "16135551234"},
+	}
+	got, err := w.SplitBroadcast(cfg, now, QueueOptions{CPSubject: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("want 3 timezone groups; got %d: %+v", len(got), got)
+	}
+	// Eastern and Toronto groups are sent immediately, Pacific last
+	last := got[len(got)-1]
+	if strings.Join(last.Cfg.ToFaxNumber, "|") != "14155551234|13105551234" || last.Cfg.FaxType != broadcast {
+		t.Fatalf("unexpected Pacific group: %+v", last.Cfg)
+	}
+	if last.Options.QueueFaxDate != "2018-03-01" || last.Options.QueueFaxTime != "11:00" || last.Options.CPSubject != "hello" {
+		t.Fatalf("unexpected Pacific options: %+v", last.Options)
+	}
+	for _, sq := range got[:2] {
+		if sq.Options.QueueFaxDate != "" || sq.Cfg.FaxType != single {
+			t.Fatalf("unexpected immediate group: %+v", sq)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package srfax
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// StatusEvent reports the SentStatus of a watched outbound fax when it is first observed
+// and every time it changes.
+type StatusEvent struct {
+	// FaxDetailsID of the fax
+	ID int
+
+	// SentStatus before the change, blank on the first observation
+	Previous string
+
+	// Current status record returned by GetMulFaxStatus
+	Result MulFaxStatusResult
+}
+
+// Terminal reports whether the fax has reached a final SentStatus. A StatusWatcher stops
+// watching a fax after emitting its terminal event.
+func (e *StatusEvent) Terminal() bool { return IsTerminalSentStatus(e.Result.SentStatus) }
+
+// StatusWatcherOptions specify optional arguments for a StatusWatcher.
+type StatusWatcherOptions struct {
+	// Delay between polls, defaults to 30 seconds
+	Interval time.Duration
+
+	// Maximum number of FaxDetailsIDs per GetMulFaxStatus request, defaults to 50
+	BatchSize int
+
+	// Optional callback invoked with errors encountered while polling.
+	// The watcher keeps polling after an error
+	OnError func(error)
+}
+
+// StatusWatcher monitors many outbound faxes at once. Watched FaxDetailsIDs are polled in
+// batches with GetMulFaxStatus, changes are emitted as StatusEvents, and faxes are
+// removed from the watch set once they reach a terminal SentStatus.
+type StatusWatcher struct {
+	c    *Client
+	opts StatusWatcherOptions
+
+	mu  sync.Mutex
+	ids map[int]string // FaxDetailsID to last seen SentStatus
+
+	events chan StatusEvent
+}
+
+// NewStatusWatcher returns a StatusWatcher with an empty watch set. Call Add to watch faxes
+// and Run to start polling.
+func (c *Client) NewStatusWatcher(opts StatusWatcherOptions) *StatusWatcher {
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+	return &StatusWatcher{c: c, opts: opts, ids: make(map[int]string), events: make(chan StatusEvent, 64)}
+}
+
+// Add starts watching the given FaxDetailsIDs, returned from QueueFax or ForwardFax.
+// It is safe to call Add while the watcher is running.
+func (w *StatusWatcher) Add(ids ...int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, id := range ids {
+		if _, ok := w.ids[id]; !ok && id > 0 {
+			w.ids[id] = ""
+		}
+	}
+}
+
+// Len returns the number of faxes currently watched.
+func (w *StatusWatcher) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.ids)
+}
+
+// Events returns the channel on which status changes are delivered. The channel is closed
+// when Run returns. Events must be received for the watcher to make progress.
+func (w *StatusWatcher) Events() <-chan StatusEvent { return w.events }
+
+// Run polls the watched faxes every Interval until ctx is cancelled, and returns ctx.Err().
+// Run must only be called once.
+func (w *StatusWatcher) Run(ctx context.Context) error {
+	defer close(w.events)
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := w.Poll(ctx); err != nil && ctx.Err() == nil && w.opts.OnError != nil {
+			w.opts.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches the status of every watched fax once and emits events for those that changed.
+// It returns the last error encountered; batches that succeeded are still processed.
+func (w *StatusWatcher) Poll(ctx context.Context) error {
+	w.mu.Lock()
+	ids := make([]string, 0, len(w.ids))
+	for id := range w.ids {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	w.mu.Unlock()
+	sort.Strings(ids)
+
+	var lastErr error
+	for _, batch := range chunkStrings(ids, w.opts.BatchSize) {
+		resp, err := w.c.getMulFaxStatus(ctx, batch)
+		if err != nil {
+			lastErr = errors.Wrap(err, "failed to poll fax status")
+			continue
+		}
+		for _, res := range resp.Result {
+			id, err := IDFromName(res.FileName)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			ev, changed := w.update(id, res)
+			if !changed {
+				continue
+			}
+			select {
+			case w.events <- ev:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return lastErr
+}
+
+// update records the latest status of id and reports whether it changed.
+func (w *StatusWatcher) update(id int, res MulFaxStatusResult) (StatusEvent, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	prev, ok := w.ids[id]
+	if !ok || prev == res.SentStatus {
+		return StatusEvent{}, false
+	}
+	ev := StatusEvent{ID: id, Previous: prev, Result: res}
+	if ev.Terminal() {
+		delete(w.ids, id)
+	} else {
+		w.ids[id] = res.SentStatus
+	}
+	return ev, true
+}
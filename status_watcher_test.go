@@ -0,0 +1,88 @@
+package srfax
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStatusWatcher(t *testing.T) {
+
+	var (
+		mu       sync.Mutex
+		requests []string
+		round    = map[string]int{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		ids := req["sFaxDetailsID"].(string)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, ids)
+		result := make([]map[string]interface{}, 0)
+		for _, id := range strings.Split(ids, "|") {
+			round[id]++
+			status := "In Progress"
+			// fax 1 is sent on the second poll, fax 2 fails on the third
+			if (id == "1" && round[id] >= 2) || (id == "2" && round[id] >= 3) {
+				status = map[string]string{"1": "Sent", "2": "Failed"}[id]
+			}
+			result = append(result, map[string]interface{}{
+				"FileName":   fmt.Sprintf("20180101230101-8812-34_0|%s", id),
+				"SentStatus": status,
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Status": "Success", "Result": result})
+	}))
+	defer srv.Close()
+
+	client := &Client{account: account{9090, "abc"}, url: srv.URL}
+	w := client.NewStatusWatcher(StatusWatcherOptions{BatchSize: 1})
+	w.Add(1, 2, 2, 0)
+	if w.Len() != 2 {
+		t.Fatalf("want 2 watched faxes; got %d", w.Len())
+	}
+
+	ctx := context.Background()
+	var got []string
+	for i := 0; i < 4; i++ {
+		if err := w.Poll(ctx); err != nil {
+			t.Fatal(err)
+		}
+	drain:
+		for {
+			select {
+			case ev := <-w.Events():
+				got = append(got, fmt.Sprintf("%d:%s->%s:%t", ev.ID, ev.Previous, ev.Result.SentStatus, ev.Terminal()))
+			default:
+				break drain
+			}
+		}
+	}
+
+	want := []string{
+		"1:->In Progress:false",
+		"2:->In Progress:false",
+		"1:In Progress->Sent:true",
+		"2:In Progress->Failed:true",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("events = %v; want %v", got, want)
+	}
+	if w.Len() != 0 {
+		t.Fatalf("want empty watch set; got %d", w.Len())
+	}
+	// batched one ID per request, and terminal faxes are no longer polled
+	if len(requests) != 5 {
+		t.Fatalf("want 5 requests; got %d: %v", len(requests), requests)
+	}
+}
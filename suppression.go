@@ -0,0 +1,181 @@
+package srfax
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SuppressionList is a do-not-fax list. When a list is supplied in ClientCfg it is consulted
+// before every QueueFax, ForwardFax and QueueBroadcast operation.
+//
+// Implementations must be safe for concurrent use.
+type SuppressionList interface {
+	// Suppressed reports whether number is on the list and the reason it was added.
+	Suppressed(number FaxNumber) (reason string, ok bool, err error)
+
+	// Suppress adds number to the list, recording reason.
+	Suppress(number FaxNumber, reason string) error
+}
+
+// SuppressedError is returned when one or more recipients are on the suppression list.
+// Numbers maps each suppressed recipient to the reason it was suppressed.
+type SuppressedError struct {
+	Numbers map[string]string
+}
+
+func (e *SuppressedError) Error() string {
+	ss := make([]string, 0, len(e.Numbers))
+	for n, reason := range e.Numbers {
+		ss = append(ss, fmt.Sprintf("%s (%s)", n, reason))
+	}
+	sort.Strings(ss)
+	return fmt.Sprintf("suppressed fax number(s): %s", strings.Join(ss, ", "))
+}
+
+// filterSuppressed splits numbers into those that may be faxed and those on the client's
+// suppression list, mapped to the reason. Numbers that cannot be parsed are looked up as is.
+func (c *Client) filterSuppressed(numbers []string) ([]string, map[string]string, error) {
+	if c.suppression == nil {
+		return numbers, nil, nil
+	}
+	allowed := make([]string, 0, len(numbers))
+	suppressed := make(map[string]string)
+	for _, s := range numbers {
+		n, err := ParseFaxNumber(s)
+		if err != nil {
+			n = FaxNumber(s)
+		}
+		reason, ok, err := c.suppression.Suppressed(n)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to check suppression list")
+		}
+		if ok {
+			suppressed[s] = reason
+			continue
+		}
+		allowed = append(allowed, s)
+	}
+	return allowed, suppressed, nil
+}
+
+// checkSuppressed returns a *SuppressedError if any of numbers are on the client's suppression list.
+func (c *Client) checkSuppressed(numbers []string) error {
+	_, suppressed, err := c.filterSuppressed(numbers)
+	if err != nil {
+		return err
+	}
+	if len(suppressed) > 0 {
+		return &SuppressedError{Numbers: suppressed}
+	}
+	return nil
+}
+
+// SuppressionEntry records why and when a number was added to a FileSuppressionList.
+type SuppressionEntry struct {
+	Reason string    `json:"reason"`
+	Added  time.Time `json:"added"`
+}
+
+// FileSuppressionList is a SuppressionList backed by a JSON file. The whole list is held in
+// memory and the file is rewritten atomically whenever a number is added.
+type FileSuppressionList struct {
+	path string
+
+	mu      sync.RWMutex
+	entries map[FaxNumber]SuppressionEntry
+}
+
+// NewFileSuppressionList loads a suppression list from path. A missing file is treated as an
+// empty list and is created on the first call to Suppress.
+func NewFileSuppressionList(path string) (*FileSuppressionList, error) {
+	l := &FileSuppressionList{path: path, entries: make(map[FaxNumber]SuppressionEntry)}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read suppression list")
+	}
+	if err := json.Unmarshal(b, &l.entries); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode suppression list %s", path)
+	}
+	return l, nil
+}
+
+// Suppressed implements SuppressionList.
+func (l *FileSuppressionList) Suppressed(number FaxNumber) (string, bool, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	e, ok := l.entries[number]
+	return e.Reason, ok, nil
+}
+
+// Suppress implements SuppressionList.
+func (l *FileSuppressionList) Suppress(number FaxNumber, reason string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.entries[number]; ok {
+		return nil
+	}
+	l.entries[number] = SuppressionEntry{Reason: reason, Added: time.Now().UTC()}
+	if err := writeJSONFile(l.path, l.entries); err != nil {
+		delete(l.entries, number)
+		return errors.Wrap(err, "failed to save suppression list")
+	}
+	return nil
+}
+
+// Entries returns a copy of all suppressed numbers.
+func (l *FileSuppressionList) Entries() map[FaxNumber]SuppressionEntry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := make(map[FaxNumber]SuppressionEntry, len(l.entries))
+	for n, e := range l.entries {
+		out[n] = e
+	}
+	return out
+}
+
+// OptOut parses a fax number as typed by the recipient and adds it to list.
+// If reason is blank it defaults to "opt-out request".
+func OptOut(list SuppressionList, number, reason string) error {
+	n, err := ParseFaxNumber(number)
+	if err != nil {
+		return err
+	}
+	if reason == "" {
+		reason = "opt-out request"
+	}
+	return list.Suppress(n, reason)
+}
+
+// OptOutHandler returns an http.Handler accepting opt-out requests, e.g., from a web form
+// referenced on a fax cover page. It expects a POST with a "number" and optional "reason"
+// form value and responds with 204 No Content once the number has been added to list.
+func OptOutHandler(list SuppressionList) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if _, err := ParseFaxNumber(r.FormValue("number")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := OptOut(list, r.FormValue("number"), r.FormValue("reason")); err != nil {
+			http.Error(w, "failed to record opt-out", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
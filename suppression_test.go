@@ -0,0 +1,136 @@
+package srfax
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileSuppressionList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "suppressed.json")
+
+	l, err := NewFileSuppressionList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := OptOut(l, "(613) 555-1234", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := OptOut(l, "not a number", ""); err == nil {
+		t.Fatal("want error for invalid number")
+	}
+
+	// reload from disk
+	l, err = NewFileSuppressionList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reason, ok, err := l.Suppressed("16135551234")
+	if err != nil || !ok || reason != "opt-out request" {
+		t.Fatalf("Suppressed = %q, %t, %v; want opt-out request, true, nil", reason, ok, err)
+	}
+	if _, ok, _ := l.Suppressed("16135550000"); ok {
+		t.Fatal("want number not suppressed")
+	}
+}
+
+func TestSuppressionEnforced(t *testing.T) {
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		sent = append(sent, req["sToFaxNumber"].(string))
+		json.NewEncoder(w).Encode(map[string]interface{}{"Status": "Success", "Result": "1234"})
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	list, err := NewFileSuppressionList(filepath.Join(dir, "suppressed.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := list.Suppress("16135551234", "complaint"); err != nil {
+		t.Fatal(err)
+	}
+
+	client := Client{account: account{9090, "abc"}, url: srv.URL, suppression: list}
+	cfg := QueueCfg{
+		CallerID:    6135550000,
+		SenderEmail: "test@example.com",
+		FaxType:     single,
+		ToFaxNumber: []string{"16135551234"},
+	}
+	files := []File{{Name: "a.txt", Content: "aGVsbG8="}}
+
+	_, err = client.QueueFax(files, cfg)
+	if e, ok := err.(*SuppressedError); !ok || e.Numbers["16135551234"] != "complaint" {
+		t.Fatalf("want *SuppressedError with reason; got %v", err)
+	}
+
+	cfg.ToFaxNumber = []string{"16135551234", "16135552222"}
+	res, err := client.QueueBroadcast(files, cfg, BroadcastOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := res.Errors["16135551234"].(*SuppressedError); !ok {
+		t.Fatalf("want suppressed recipient reported; got %v", res.Errors)
+	}
+	if res.IDs["16135552222"] != "1234" {
+		t.Fatalf("want allowed recipient queued; got %v", res.IDs)
+	}
+	if len(sent) != 1 || sent[0] != "16135552222" {
+		t.Fatalf("want only allowed recipient sent; got %v", sent)
+	}
+}
+
+func TestOptOutHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "srfax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	list, err := NewFileSuppressionList(filepath.Join(dir, "suppressed.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := OptOutHandler(list)
+
+	var tests = []struct {
+		method string
+		form   url.Values
+		want   int
+	}{
+		{http.MethodPost, url.Values{"number": {"613-555-1234"}, "reason": {"web form"}}, http.StatusNoContent},
+		{http.MethodPost, url.Values{"number": {"555"}}, http.StatusBadRequest},
+		{http.MethodGet, nil, http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, "/optout", strings.NewReader(test.form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != test.want {
+			t.Fatalf("%s %v = %d; want %d", test.method, test.form, w.Code, test.want)
+		}
+	}
+	if reason, ok, _ := list.Suppressed("16135551234"); !ok || reason != "web form" {
+		t.Fatalf("want number suppressed with reason; got %q, %t", reason, ok)
+	}
+}
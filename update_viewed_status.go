@@ -1,6 +1,8 @@
 package srfax
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 )
 
@@ -57,6 +59,10 @@ func newViewedStatusOperation(c *Client, cfg *ViewedStatusCfg) *viewedStatusOper
 
 // UpdateViewedStatus marks an inbound or outbound fax as read or unread.
 func (c *Client) UpdateViewedStatus(cfg ViewedStatusCfg) (*ViewedStatus, error) {
+	return c.updateViewedStatus(context.Background(), cfg)
+}
+
+func (c *Client) updateViewedStatus(ctx context.Context, cfg ViewedStatusCfg) (*ViewedStatus, error) {
 	if err := cfg.validate(); err != nil {
 		return nil, err
 	}
@@ -67,7 +73,7 @@ func (c *Client) UpdateViewedStatus(cfg ViewedStatusCfg) (*ViewedStatus, error)
 	}
 
 	result := mappedViewedStatus{}
-	if err := run(operation, &result, c.url); err != nil {
+	if err := runContext(ctx, operation, &result, c.url); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,103 @@
+package srfax
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// IsTerminalSentStatus reports whether an outbound fax SentStatus is final, i.e., the fax
+// was either sent or failed and its status will not change again.
+func IsTerminalSentStatus(status string) bool {
+	return strings.EqualFold(status, sentStatusSent) || strings.EqualFold(status, sentStatusFailed)
+}
+
+// WaitOptions specify optional arguments when waiting for an outbound fax to complete.
+type WaitOptions struct {
+	// Delay before the first poll, and after each poll where the status changed.
+	// Defaults to 5 seconds
+	MinInterval time.Duration
+
+	// The delay grows by half after every poll where the status did not change,
+	// up to MaxInterval. Defaults to 1 minute
+	MaxInterval time.Duration
+
+	// Optional callback invoked after every poll with the attempt number, starting at 1,
+	// and either the current status or the error returned by GetFaxStatus
+	OnPoll func(attempt int, status *FaxStatus, err error)
+}
+
+func (o *WaitOptions) validate() error {
+	if o.MinInterval < 0 || o.MaxInterval < 0 {
+		return errors.New("MinInterval and MaxInterval cannot be negative")
+	}
+	if o.MinInterval == 0 {
+		o.MinInterval = 5 * time.Second
+	}
+	if o.MaxInterval == 0 {
+		o.MaxInterval = time.Minute
+	}
+	if o.MaxInterval < o.MinInterval {
+		return errors.New("MaxInterval cannot be less than MinInterval")
+	}
+	return nil
+}
+
+// WaitForDelivery polls GetFaxStatus until the outbound fax identified by id, the FaxDetailsID
+// returned from QueueFax or ForwardFax, has a terminal SentStatus and returns the final status.
+// A fax that failed to send is not an error, check SentStatus of the result.
+//
+// Polling backs off while the status is unchanged. Network errors are retried, while a
+// Failed response from SRFax (a *ResultError) or cancellation of ctx stops polling and
+// is returned.
+func (c *Client) WaitForDelivery(ctx context.Context, id int, opts WaitOptions) (*FaxStatus, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	var (
+		last     string
+		interval = opts.MinInterval
+		timer    = time.NewTimer(interval)
+	)
+	defer timer.Stop()
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		status, err := c.getFaxStatus(ctx, id)
+		if opts.OnPoll != nil {
+			opts.OnPoll(attempt, status, err)
+		}
+		switch {
+		case err != nil:
+			if _, ok := errors.Cause(err).(*ResultError); ok {
+				return nil, err
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+		case status.Result == nil:
+			return nil, errors.Errorf("missing Result in status of fax %d", id)
+		case IsTerminalSentStatus(status.Result.SentStatus):
+			return status, nil
+		}
+
+		if err == nil && status.Result.SentStatus != last {
+			last = status.Result.SentStatus
+			interval = opts.MinInterval
+		} else {
+			interval += interval / 2
+			if interval > opts.MaxInterval {
+				interval = opts.MaxInterval
+			}
+		}
+		timer.Reset(interval)
+	}
+}
@@ -0,0 +1,94 @@
+package srfax
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitForDelivery(t *testing.T) {
+
+	var polls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&polls, 1)
+		status := "In Progress"
+		switch {
+		case n == 2:
+			// transient failure, should be retried
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		case n >= 4:
+			status = "Sent"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Status": "Success",
+			"Result": map[string]interface{}{"FileName": "20180101230101-8812-34_0|31524120", "SentStatus": status, "Pages": "2"},
+		})
+	}))
+	defer srv.Close()
+
+	client := Client{account: account{9090, "abc"}, url: srv.URL}
+
+	var attempts []int
+	opts := WaitOptions{
+		MinInterval: time.Millisecond,
+		MaxInterval: 5 * time.Millisecond,
+		OnPoll:      func(attempt int, _ *FaxStatus, _ error) { attempts = append(attempts, attempt) },
+	}
+	got, err := client.WaitForDelivery(context.Background(), 31524120, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Result.SentStatus != "Sent" || got.Result.Pages != 2 {
+		t.Fatalf("unexpected final status: %+v", got.Result)
+	}
+	if len(attempts) != 4 {
+		t.Fatalf("want 4 polls; got %v", attempts)
+	}
+}
+
+func TestWaitForDeliveryStops(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/failed" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"Status": "Failed", "Result": "Invalid Fax ID"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Status": "Success",
+			"Result": map[string]interface{}{"SentStatus": "In Progress"},
+		})
+	}))
+	defer srv.Close()
+
+	opts := WaitOptions{MinInterval: time.Millisecond, MaxInterval: 2 * time.Millisecond}
+
+	t.Run("context cancelled", func(t *testing.T) {
+		client := Client{account: account{9090, "abc"}, url: srv.URL}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := client.WaitForDelivery(ctx, 1, opts); err != context.DeadlineExceeded {
+			t.Fatalf("want context.DeadlineExceeded; got %v", err)
+		}
+	})
+
+	t.Run("failed response", func(t *testing.T) {
+		client := Client{account: account{9090, "abc"}, url: srv.URL + "/failed"}
+		_, err := client.WaitForDelivery(context.Background(), 1, opts)
+		if err == nil {
+			t.Fatal("want error for Failed response")
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		client := Client{account: account{9090, "abc"}, url: srv.URL}
+		_, err := client.WaitForDelivery(context.Background(), 1, WaitOptions{MinInterval: time.Minute, MaxInterval: time.Second})
+		if err == nil {
+			t.Fatal("want error for MaxInterval < MinInterval")
+		}
+	})
+}